@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	//Every API key starts with it, so leaked keys are easy to spot
+	apiKeyMarker = "mef_"
+	//Number of random bytes in an API key
+	apiKeySize = 32
+	//Characters of the key kept in the clear so users can tell their keys apart
+	apiKeyPrefixLength = len(apiKeyMarker) + 8
+	//The last use of a key is written at most this often
+	apiKeyLastUsedInterval = time.Minute
+)
+
+var (
+	errInvalidAPIKey = errors.New("invalid api key")
+	errExpiredAPIKey = errors.New("api key has expired")
+	errStaleAPIKey   = errors.New("api key has been invalidated")
+)
+
+type apiKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(key db.ApiKey) apiKeyResponse {
+	rsp := apiKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+	}
+	if key.ExpiresAt.Valid {
+		rsp.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		rsp.LastUsedAt = &key.LastUsedAt.Time
+	}
+	return rsp
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=64"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type createAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey apiKeyResponse `json:"api_key"`
+}
+
+//createAPIKey issues a key for scripts, the key itself is only shown in this response
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// A key never gets more than the token or key it was created with, which may be less than the role allows
+	for _, scope := range req.Scopes {
+		if !util.IsScopeAllowed(authPayload.Role, scope) || !authPayload.HasScope(scope) {
+			err := fmt.Errorf("scope %s is not allowed", scope)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	expiresAt := sql.NullTime{}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			err := errors.New("expires_at must be in the future")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	key, err := newAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	apiKey, err := server.store.CreateApiKey(ctx, db.CreateApiKeyParams{
+		UserName:        authPayload.UserName,
+		Name:            req.Name,
+		Prefix:          key[:apiKeyPrefixLength],
+		KeyHash:         util.HashToken(key),
+		Scopes:          req.Scopes,
+		ExpiresAt:       expiresAt,
+		TokenGeneration: authPayload.Generation,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createAPIKeyResponse{
+		Key:    key,
+		APIKey: newAPIKeyResponse(apiKey),
+	})
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	apiKeys, err := server.store.ListApiKeysByUser(ctx, authPayload.UserName)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]apiKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		rsp[i] = newAPIKeyResponse(apiKey)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type deleteAPIKeyRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+func (server *Server) deleteAPIKey(ctx *gin.Context) {
+	var req deleteAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	deleted, err := server.store.DeleteApiKey(ctx, db.DeleteApiKeyParams{
+		ID:       uuid.MustParse(req.ID),
+		UserName: authPayload.UserName,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Keys of other users are reported as missing too
+	if deleted == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+//newAPIKey returns a random key, e.g. mef_mzxw6ytboi4dgnzq...
+func newAPIKey() (string, error) {
+	b := make([]byte, apiKeySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyMarker + strings.ToLower(recoveryCodeEncoding.EncodeToString(b)), nil
+}
+
+//verifyAPIKey builds the payload of a request authenticated with an API key.
+//The scopes a key was issued with only count as long as the role of its owner allows them,
+//the key itself only until the tokens of its owner are invalidated, e.g. by a password reset.
+func verifyAPIKey(ctx context.Context, store db.Store, key string) (*token.Payload, error) {
+	apiKey, err := store.GetApiKeyByHash(ctx, util.HashToken(key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		return nil, errExpiredAPIKey
+	}
+
+	if apiKey.TokenGeneration < apiKey.UserTokenGeneration {
+		return nil, errStaleAPIKey
+	}
+
+	scopes := []string{}
+	for _, scope := range apiKey.Scopes {
+		if util.IsScopeAllowed(apiKey.Role, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if !apiKey.LastUsedAt.Valid || time.Since(apiKey.LastUsedAt.Time) >= apiKeyLastUsedInterval {
+		if err := store.UpdateApiKeyLastUsed(ctx, apiKey.ID); err != nil {
+			log.Println("cannot update api key last use:", err)
+		}
+	}
+
+	return &token.Payload{
+		ID:         apiKey.ID,
+		UserName:   apiKey.UserName,
+		Role:       apiKey.Role,
+		Generation: apiKey.TokenGeneration,
+		Scopes:     scopes,
+		IssuedAt:   apiKey.CreatedAt,
+		NotBefore:  apiKey.CreatedAt,
+		ExpiredAt:  apiKey.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,654 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func randomAPIKey(t *testing.T, user db.User) (key string, apiKey db.ApiKey) {
+	key, err := newAPIKey()
+	require.NoError(t, err)
+
+	apiKey = db.ApiKey{
+		ID:              uuid.New(),
+		UserName:        user.UserName,
+		Name:            util.RandomString(8),
+		Prefix:          key[:apiKeyPrefixLength],
+		KeyHash:         util.HashToken(key),
+		Scopes:          []string{util.PostsWriteScope},
+		CreatedAt:       time.Now().Add(-time.Hour),
+		TokenGeneration: user.TokenGeneration,
+	}
+	return
+}
+
+func apiKeyRow(apiKey db.ApiKey, user db.User) db.GetApiKeyByHashRow {
+	return db.GetApiKeyByHashRow{
+		ID:                  apiKey.ID,
+		UserName:            apiKey.UserName,
+		Name:                apiKey.Name,
+		Prefix:              apiKey.Prefix,
+		KeyHash:             apiKey.KeyHash,
+		Scopes:              apiKey.Scopes,
+		ExpiresAt:           apiKey.ExpiresAt,
+		LastUsedAt:          apiKey.LastUsedAt,
+		CreatedAt:           apiKey.CreatedAt,
+		TokenGeneration:     apiKey.TokenGeneration,
+		Role:                user.Role,
+		UserTokenGeneration: user.TokenGeneration,
+	}
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"name":       "announcements",
+				"scopes":     []string{util.PostsWriteScope, util.CommentsWriteScope},
+				"expires_at": expiresAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.UserName, arg.UserName)
+						require.Equal(t, "announcements", arg.Name)
+						require.Equal(t, []string{util.PostsWriteScope, util.CommentsWriteScope}, arg.Scopes)
+						require.True(t, arg.ExpiresAt.Valid)
+						require.WithinDuration(t, expiresAt, arg.ExpiresAt.Time, time.Second)
+						require.Len(t, arg.Prefix, apiKeyPrefixLength)
+						require.True(t, strings.HasPrefix(arg.Prefix, apiKeyMarker))
+						// The generation of the token the key was created with
+						require.Equal(t, int32(0), arg.TokenGeneration)
+
+						return db.ApiKey{
+							ID:        uuid.New(),
+							UserName:  arg.UserName,
+							Name:      arg.Name,
+							Prefix:    arg.Prefix,
+							KeyHash:   arg.KeyHash,
+							Scopes:    arg.Scopes,
+							ExpiresAt: arg.ExpiresAt,
+							CreatedAt: time.Now(),
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp createAPIKeyResponse
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+
+				require.True(t, strings.HasPrefix(rsp.Key, rsp.APIKey.Prefix))
+				require.Equal(t, "announcements", rsp.APIKey.Name)
+				require.NotNil(t, rsp.APIKey.ExpiresAt)
+				require.Nil(t, rsp.APIKey.LastUsedAt)
+			},
+		},
+		{
+			name: "NoExpiry",
+			body: gin.H{
+				"name":   "announcements",
+				"scopes": []string{util.PostsWriteScope},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+						require.False(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{ID: uuid.New(), UserName: arg.UserName, Scopes: arg.Scopes}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "ScopeNotAllowed",
+			body: gin.H{
+				"name":   "announcements",
+				"scopes": []string{util.PostsWriteScope, util.UsersAdminScope},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{
+				"name":   "announcements",
+				"scopes": []string{},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiryInThePast",
+			body: gin.H{
+				"name":       "announcements",
+				"scopes":     []string{util.PostsWriteScope},
+				"expires_at": time.Now().Add(-time.Minute),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"name":   "announcements",
+				"scopes": []string{util.PostsWriteScope},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/api/users/me/api_keys"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.UserName, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestCreateAPIKeyWithAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t)
+	key, apiKey := randomAPIKey(t, user)
+	apiKey.Scopes = []string{util.AccountScope}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetApiKeyByHash(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(apiKeyRow(apiKey, user), nil)
+	store.EXPECT().
+		UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+	store.EXPECT().
+		CreateApiKey(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	// The role of the user allows posts:write, the key does not have it
+	data, err := json.Marshal(gin.H{
+		"name":   "announcements",
+		"scopes": []string{util.AccountScope, util.PostsWriteScope},
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/api/users/me/api_keys", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, "ApiKey "+key)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	_, apiKey := randomAPIKey(t, user)
+	apiKey.LastUsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListApiKeysByUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return([]db.ApiKey{apiKey}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp []gin.H
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp, 1)
+				require.Equal(t, apiKey.ID.String(), rsp[0]["id"])
+				require.Equal(t, apiKey.Prefix, rsp[0]["prefix"])
+				require.NotNil(t, rsp[0]["last_used_at"])
+				require.Nil(t, rsp[0]["expires_at"])
+				require.NotContains(t, rsp[0], "key_hash")
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListApiKeysByUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/api/users/me/api_keys"
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.UserName, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDeleteAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	id := uuid.New()
+
+	testCases := []struct {
+		name          string
+		id            string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   id.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteApiKey(gomock.Any(), gomock.Eq(db.DeleteApiKeyParams{ID: id, UserName: user.UserName})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   id.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   "not-a-uuid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			id:   id.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/users/me/api_keys/%s", tc.id)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.UserName, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	user, _ := randomUser(t)
+	key, apiKey := randomAPIKey(t, user)
+
+	recentKey, recentAPIKey := randomAPIKey(t, user)
+	recentAPIKey.LastUsedAt = sql.NullTime{Time: time.Now().Add(-10 * time.Second), Valid: true}
+
+	expiredKey, expiredAPIKey := randomAPIKey(t, user)
+	expiredAPIKey.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+
+	// The password of the owner has been reset since the key was issued
+	resetUser := user
+	resetUser.TokenGeneration++
+
+	// The owner has been demoted since the key was issued
+	demotedKey, demotedAPIKey := randomAPIKey(t, user)
+	demotedAPIKey.Scopes = []string{util.PostsWriteScope, util.UsersAdminScope}
+
+	testCases := []struct {
+		name          string
+		key           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload)
+	}{
+		{
+			name: "OK",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApiKeyByHash(gomock.Any(), gomock.Eq(util.HashToken(key))).
+					Times(1).
+					Return(apiKeyRow(apiKey, user), nil)
+				store.EXPECT().
+					UpdateApiKeyLastUsed(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, apiKey.ID, payload.ID)
+				require.Equal(t, user.UserName, payload.UserName)
+				require.Equal(t, user.Role, payload.Role)
+				require.Equal(t, []string{util.PostsWriteScope}, payload.Scopes)
+			},
+		},
+		{
+			name: "LastUsedNotWritten",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApiKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(apiKeyRow(apiKey, user), nil)
+				store.EXPECT().
+					UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RecentlyUsed",
+			key:  recentKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApiKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(apiKeyRow(recentAPIKey, user), nil)
+				store.EXPECT().
+					UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ScopesOfRole",
+			key:  demotedKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApiKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(apiKeyRow(demotedAPIKey, user), nil)
+				store.EXPECT().
+					UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, []string{util.PostsWriteScope}, payload.Scopes)
+			},
+		},
+		{
+			name: "UnknownKey",
+			key:  "mef_unknown",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApiKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetApiKeyByHashRow{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyError(t, recorder.Body, errInvalidAPIKey)
+			},
+		},
+		{
+			name: "ExpiredKey",
+			key:  expiredKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApiKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(apiKeyRow(expiredAPIKey, user), nil)
+				store.EXPECT().
+					UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyError(t, recorder.Body, errExpiredAPIKey)
+			},
+		},
+		{
+			name: "StaleKey",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApiKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(apiKeyRow(apiKey, resetUser), nil)
+				store.EXPECT().
+					UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyError(t, recorder.Body, errStaleAPIKey)
+			},
+		},
+		{
+			name: "InternalError",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetApiKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetApiKeyByHashRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			var payload *token.Payload
+			authPath := "/api/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.revocations, server.generations, server.store),
+				func(ctx *gin.Context) {
+					payload = ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			request.Header.Set(authorizationHeaderKey, "ApiKey "+tc.key)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, payload)
+		})
+	}
+}
+
+func TestLogoutWithAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t)
+	key, apiKey := randomAPIKey(t, user)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetApiKeyByHash(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(apiKeyRow(apiKey, user), nil)
+	store.EXPECT().
+		UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+	store.EXPECT().
+		RevokeToken(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodPost, "/api/users/logout", nil)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, "ApiKey "+key)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type categoryRequestID struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type categoryRequest struct {
+	Slug        string `json:"slug" binding:"required,slug,max=64"`
+	Name        string `json:"name" binding:"required,max=128"`
+	Description string `json:"description"`
+}
+
+func (server *Server) listCategories(ctx *gin.Context) {
+
+	categories, err := server.store.ListCategories(ctx)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, categories)
+
+}
+
+func (server *Server) getCategory(ctx *gin.Context) {
+
+	var id categoryRequestID
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	category, err := server.store.GetCategory(ctx, id.ID)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+
+}
+
+func (server *Server) createCategory(ctx *gin.Context) {
+
+	var req categoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	arg := db.CreateCategoryParams{
+
+		Slug:        req.Slug,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	category, err := server.store.CreateCategory(ctx, arg)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+
+	}
+
+	ctx.JSON(http.StatusCreated, category)
+
+}
+
+func (server *Server) updateCategory(ctx *gin.Context) {
+
+	var id categoryRequestID
+	var req categoryRequest
+
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	arg := db.UpdateCategoryParams{
+
+		ID:          id.ID,
+		Slug:        req.Slug,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	category, err := server.store.UpdateCategory(ctx, arg)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+
+}
+
+func (server *Server) deleteCategory(ctx *gin.Context) {
+
+	var id categoryRequestID
+
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	_, err := server.store.GetCategory(ctx, id.ID)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.DeleteCategory(ctx, id.ID)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+
+}
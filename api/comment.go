@@ -0,0 +1,308 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+)
+
+type postCommentsRequestID struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type createCommentRequest struct {
+	ParentID int64  `json:"parent_id" binding:"omitempty,min=1"`
+	Body     string `json:"body" binding:"required"`
+}
+
+type commentRequestID struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type updateCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+type commentResponse struct {
+	ID        int64              `json:"id"`
+	PostID    int64              `json:"post_id"`
+	Author    string             `json:"author"`
+	ParentID  *int64             `json:"parent_id"`
+	Body      string             `json:"body"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	Replies   []*commentResponse `json:"replies"`
+}
+
+func newCommentResponse(comment db.Comment) *commentResponse {
+	resp := &commentResponse{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		Author:    comment.Author,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		Replies:   []*commentResponse{},
+	}
+
+	if comment.ParentID.Valid {
+		parentID := comment.ParentID.Int64
+		resp.ParentID = &parentID
+	}
+
+	return resp
+}
+
+// newCommentTree nests the replies of a post under their parent comments,
+// keeping the order in which the comments are given
+func newCommentTree(comments []db.Comment) []*commentResponse {
+	nodes := make(map[int64]*commentResponse, len(comments))
+	for _, comment := range comments {
+		nodes[comment.ID] = newCommentResponse(comment)
+	}
+
+	roots := []*commentResponse{}
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+
+		parent, ok := nodes[comment.ParentID.Int64]
+		if comment.ParentID.Valid && ok {
+			parent.Replies = append(parent.Replies, node)
+			continue
+		}
+
+		roots = append(roots, node)
+	}
+
+	return roots
+}
+
+func (server *Server) listComments(ctx *gin.Context) {
+
+	var req postCommentsRequestID
+	if err := ctx.ShouldBindUri(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	_, err := server.store.GetPost(ctx, req.ID)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	comments, err := server.store.ListCommentsByPost(ctx, req.ID)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentTree(comments))
+
+}
+
+func (server *Server) createComment(ctx *gin.Context) {
+
+	var id postCommentsRequestID
+	var req createCommentRequest
+
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	_, err := server.store.GetPost(ctx, id.ID)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var parentID sql.NullInt64
+
+	if req.ParentID != 0 {
+
+		parent, err := server.store.GetComment(ctx, req.ParentID)
+
+		if err != nil {
+
+			if err == sql.ErrNoRows {
+
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if parent.PostID != id.ID {
+
+			err := errors.New("parent comment belongs to another post")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+
+		}
+
+		parentID = sql.NullInt64{Int64: parent.ID, Valid: true}
+
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.CreateCommentParams{
+
+		PostID:   id.ID,
+		Author:   authPayload.UserName,
+		ParentID: parentID,
+		Body:     req.Body,
+	}
+
+	comment, err := server.store.CreateComment(ctx, arg)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+
+	}
+
+	ctx.JSON(http.StatusCreated, newCommentResponse(comment))
+
+}
+
+func (server *Server) updateComment(ctx *gin.Context) {
+
+	var id commentRequestID
+	var req updateCommentRequest
+
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if _, ok := server.getOwnedComment(ctx, id.ID); !ok {
+		return
+	}
+
+	comment, err := server.store.UpdateComment(ctx, db.UpdateCommentParams{
+		ID:   id.ID,
+		Body: req.Body,
+	})
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(comment))
+
+}
+
+func (server *Server) deleteComment(ctx *gin.Context) {
+
+	var id commentRequestID
+
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if _, ok := server.getOwnedComment(ctx, id.ID); !ok {
+		return
+	}
+
+	err := server.store.DeleteComment(ctx, id.ID)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+
+}
+
+// getOwnedComment fetches the comment and checks that the authenticated user wrote it,
+// moderators and admins may manage any comment.
+// The error response is already written when false is returned.
+func (server *Server) getOwnedComment(ctx *gin.Context, id int64) (db.Comment, bool) {
+
+	comment, err := server.store.GetComment(ctx, id)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return comment, false
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return comment, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if comment.Author != authPayload.UserName && !hasRole(authPayload, util.ModeratorRole, util.AdminRole) {
+
+		err := errors.New("comment doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return comment, false
+
+	}
+
+	return comment, true
+
+}
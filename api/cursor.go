@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+)
+
+var errInvalidCursor = errors.New("cursor is invalid")
+
+//Position of a post in the (created_at, id) ordering, handed to clients as an opaque string
+type postCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func newPostCursor(post db.Post) postCursor {
+	return postCursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+func (cursor postCursor) encode() string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + strconv.FormatInt(cursor.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePostCursor(value string) (postCursor, error) {
+	var cursor postCursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ",")
+	if len(parts) != 2 {
+		return cursor, errInvalidCursor
+	}
+
+	cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+
+	cursor.ID, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || cursor.ID < 1 {
+		return cursor, errInvalidCursor
+	}
+
+	return cursor, nil
+}
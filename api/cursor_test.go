@@ -0,0 +1,41 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostCursor(t *testing.T) {
+	cursor := postCursor{
+		CreatedAt: time.Date(2022, 10, 5, 14, 3, 7, 123456000, time.UTC),
+		ID:        42,
+	}
+
+	decoded, err := decodePostCursor(cursor.encode())
+	require.NoError(t, err)
+	require.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	require.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecodeInvalidPostCursor(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+	}{
+		{name: "NotBase64", value: "%%%"},
+		{name: "MissingID", value: "MjAyMi0xMC0wNVQxNDowMzowN1o"},
+		{name: "InvalidTime", value: "bm90LWEtdGltZSw0Mg"},
+		{name: "InvalidID", value: "MjAyMi0xMC0wNVQxNDowMzowN1osMA"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodePostCursor(tc.value)
+			require.ErrorIs(t, err, errInvalidCursor)
+		})
+	}
+}
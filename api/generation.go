@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+)
+
+// How often the in-memory token generations are synced with the DB
+const generationSyncInterval = time.Minute
+
+// generationList is an in-memory cache of the token generation of every user
+// whose tokens have been invalidated at least once, e.g. by a password reset.
+// Tokens carry the generation they were issued at and authMiddleware rejects
+// the ones that are older than the cached generation of their user.
+type generationList struct {
+	store       db.Store
+	mu          sync.RWMutex
+	generations map[string]int32
+}
+
+func newGenerationList(store db.Store) *generationList {
+	return &generationList{
+		store:       store,
+		generations: make(map[string]int32),
+	}
+}
+
+// set records the current generation of the user, an older value never replaces a newer one
+func (list *generationList) set(userName string, generation int32) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	if generation > list.generations[userName] {
+		list.generations[userName] = generation
+	}
+}
+
+// isStale checks if a token issued at the given generation has been invalidated since
+func (list *generationList) isStale(userName string, generation int32) bool {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+
+	return generation < list.generations[userName]
+}
+
+// load replaces the cache with the generations stored in the DB
+func (list *generationList) load(ctx context.Context) error {
+	rows, err := list.store.ListTokenGenerations(ctx)
+	if err != nil {
+		return err
+	}
+
+	generations := make(map[string]int32, len(rows))
+	for _, row := range rows {
+		generations[row.UserName] = row.TokenGeneration
+	}
+
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	list.generations = generations
+	return nil
+}
+
+// sync reloads the cache every interval until ctx is done
+func (list *generationList) sync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := list.load(ctx); err != nil {
+				log.Println("cannot load token generations:", err)
+			}
+		}
+	}
+}
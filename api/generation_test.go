@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerationListLoad(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListTokenGenerations(gomock.Any()).
+		Times(1).
+		Return([]db.ListTokenGenerationsRow{{UserName: "user", TokenGeneration: 2}}, nil)
+
+	list := newGenerationList(store)
+	list.set("stale", 1)
+
+	err := list.load(context.Background())
+	require.NoError(t, err)
+
+	require.True(t, list.isStale("user", 1))
+	require.False(t, list.isStale("user", 2))
+	require.False(t, list.isStale("stale", 0))
+	require.False(t, list.isStale("other", 0))
+}
+
+func TestGenerationListLoadError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListTokenGenerations(gomock.Any()).
+		Times(1).
+		Return([]db.ListTokenGenerationsRow{}, sql.ErrConnDone)
+
+	list := newGenerationList(store)
+	list.set("user", 1)
+
+	err := list.load(context.Background())
+	require.Error(t, err)
+
+	// A failed reload keeps the generations that were already cached
+	require.True(t, list.isStale("user", 0))
+}
+
+func TestGenerationListSet(t *testing.T) {
+	list := newGenerationList(nil)
+
+	list.set("user", 2)
+	list.set("user", 1)
+
+	require.True(t, list.isStale("user", 1))
+	require.False(t, list.isStale("user", 2))
+}
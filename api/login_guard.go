@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+)
+
+// How often forgotten login attempts are dropped from memory
+const loginAttemptPruneInterval = 5 * time.Minute
+
+const (
+	lockoutKindUser = "user"
+	lockoutKindIP   = "ip"
+)
+
+// Same response for an unknown user and a wrong password so accounts cannot be enumerated
+var errInvalidCredentials = errors.New("incorrect user name or password")
+
+var errTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+// Failed logins of a single user name or client IP
+type loginAttempt struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// loginGuard tracks failed logins per user name and per client IP.
+// Every failure makes the caller wait twice as long as the previous one
+// before the next try, and reaching the maximum number of failures locks
+// the user name or IP out for the lockout duration. Failures are forgotten
+// once a whole lockout duration passes without a new one.
+type loginGuard struct {
+	maxAttempts      int
+	maxAttemptsPerIP int
+	baseBackoff      time.Duration
+	maxBackoff       time.Duration
+	lockoutDuration  time.Duration
+
+	mu    sync.Mutex
+	users map[string]*loginAttempt
+	ips   map[string]*loginAttempt
+}
+
+func newLoginGuard(config util.Config) *loginGuard {
+	return &loginGuard{
+		maxAttempts:      config.LoginMaxAttempts,
+		maxAttemptsPerIP: config.LoginMaxAttemptsPerIP,
+		baseBackoff:      config.LoginBackoffBase,
+		maxBackoff:       config.LoginBackoffMax,
+		lockoutDuration:  config.LoginLockoutDuration,
+		users:            make(map[string]*loginAttempt),
+		ips:              make(map[string]*loginAttempt),
+	}
+}
+
+// retryAfter returns how long the user name and IP have to wait before the next try, zero if they may try now
+func (guard *loginGuard) retryAfter(userName string, ip string) time.Duration {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	now := time.Now()
+	wait := time.Duration(0)
+
+	for _, attempt := range []*loginAttempt{guard.users[userName], guard.ips[ip]} {
+		if attempt != nil && attempt.blockedUntil.Sub(now) > wait {
+			wait = attempt.blockedUntil.Sub(now)
+		}
+	}
+
+	return wait
+}
+
+// fail records a failed login of the user name from the IP
+func (guard *loginGuard) fail(userName string, ip string) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	now := time.Now()
+	guard.record(guard.users, userName, guard.maxAttempts, now)
+	guard.record(guard.ips, ip, guard.maxAttemptsPerIP, now)
+}
+
+func (guard *loginGuard) record(attempts map[string]*loginAttempt, key string, maxAttempts int, now time.Time) {
+	attempt, ok := attempts[key]
+	if !ok || guard.isForgotten(attempt, now) {
+		attempt = &loginAttempt{}
+		attempts[key] = attempt
+	}
+
+	attempt.failures++
+	attempt.lastFailure = now
+
+	if maxAttempts > 0 && attempt.failures >= maxAttempts {
+		attempt.blockedUntil = now.Add(guard.lockoutDuration)
+		return
+	}
+
+	attempt.blockedUntil = now.Add(guard.backoff(attempt.failures))
+}
+
+// backoff doubles the base wait for every failure, up to the maximum
+func (guard *loginGuard) backoff(failures int) time.Duration {
+	wait := guard.baseBackoff
+	for i := 1; i < failures && wait < guard.maxBackoff; i++ {
+		wait *= 2
+	}
+
+	if guard.maxBackoff > 0 && wait > guard.maxBackoff {
+		wait = guard.maxBackoff
+	}
+
+	return wait
+}
+
+// succeed forgets the failures of the user name, the ones of the IP are kept
+// so a single valid account cannot be used to reset the limit of the IP
+func (guard *loginGuard) succeed(userName string) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	delete(guard.users, userName)
+}
+
+// unlock forgets the failures of the user name, returns false if there were none
+func (guard *loginGuard) unlock(userName string) bool {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	_, ok := guard.users[userName]
+	delete(guard.users, userName)
+	return ok
+}
+
+func (guard *loginGuard) isForgotten(attempt *loginAttempt, now time.Time) bool {
+	return now.After(attempt.blockedUntil) && now.Sub(attempt.lastFailure) > guard.lockoutDuration
+}
+
+// prune drops the attempts that are forgotten by now
+func (guard *loginGuard) prune() {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	now := time.Now()
+	for _, attempts := range []map[string]*loginAttempt{guard.users, guard.ips} {
+		for key, attempt := range attempts {
+			if guard.isForgotten(attempt, now) {
+				delete(attempts, key)
+			}
+		}
+	}
+}
+
+// expire prunes the forgotten attempts every interval until ctx is done
+func (guard *loginGuard) expire(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			guard.prune()
+		}
+	}
+}
+
+// isLoginLockedOut answers 429 when the user name or IP has to wait before trying to log in again
+func (server *Server) isLoginLockedOut(ctx *gin.Context, userName string, clientIP string) bool {
+	wait := server.loginGuard.retryAfter(userName, clientIP)
+	if wait <= 0 {
+		return false
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	ctx.JSON(http.StatusTooManyRequests, errorResponse(errTooManyLoginAttempts))
+	return true
+}
+
+var (
+	dummyPasswordOnce sync.Once
+	dummyPasswordHash string
+)
+
+// checkDummyPassword compares the password against a throwaway bcrypt hash,
+// so a login for an unknown user takes as long as one with a wrong password
+func checkDummyPassword(password string) {
+	dummyPasswordOnce.Do(func() {
+		dummyPasswordHash, _ = util.HashPassword(util.RandomString(16))
+	})
+
+	util.CheckPassword(password, dummyPasswordHash)
+}
+
+type loginLockoutResponse struct {
+	Kind          string    `json:"kind"`
+	Key           string    `json:"key"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	BlockedUntil  time.Time `json:"blocked_until"`
+	IsLocked      bool      `json:"is_locked"`
+}
+
+// lockouts lists the user names and IPs with failures that are not forgotten yet
+func (guard *loginGuard) lockouts() []loginLockoutResponse {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+
+	now := time.Now()
+	lockouts := []loginLockoutResponse{}
+
+	add := func(kind string, attempts map[string]*loginAttempt, maxAttempts int) {
+		for key, attempt := range attempts {
+			if guard.isForgotten(attempt, now) {
+				continue
+			}
+			lockouts = append(lockouts, loginLockoutResponse{
+				Kind:          kind,
+				Key:           key,
+				Failures:      attempt.failures,
+				LastFailureAt: attempt.lastFailure,
+				BlockedUntil:  attempt.blockedUntil,
+				IsLocked:      maxAttempts > 0 && attempt.failures >= maxAttempts && now.Before(attempt.blockedUntil),
+			})
+		}
+	}
+	add(lockoutKindUser, guard.users, guard.maxAttempts)
+	add(lockoutKindIP, guard.ips, guard.maxAttemptsPerIP)
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		if lockouts[i].Kind != lockouts[j].Kind {
+			return lockouts[i].Kind > lockouts[j].Kind
+		}
+		return lockouts[i].Key < lockouts[j].Key
+	})
+
+	return lockouts
+}
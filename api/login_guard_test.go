@@ -0,0 +1,271 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLoginGuard() *loginGuard {
+	return newLoginGuard(util.Config{
+		LoginMaxAttempts:      3,
+		LoginMaxAttemptsPerIP: 5,
+		LoginBackoffBase:      time.Second,
+		LoginBackoffMax:       4 * time.Second,
+		LoginLockoutDuration:  time.Hour,
+	})
+}
+
+func TestLoginGuardBackoff(t *testing.T) {
+	guard := newTestLoginGuard()
+
+	require.Equal(t, time.Second, guard.backoff(1))
+	require.Equal(t, 2*time.Second, guard.backoff(2))
+	require.Equal(t, 4*time.Second, guard.backoff(3))
+	require.Equal(t, 4*time.Second, guard.backoff(10))
+}
+
+func TestLoginGuardLockout(t *testing.T) {
+	guard := newTestLoginGuard()
+	require.Zero(t, guard.retryAfter("user", "127.0.0.1"))
+
+	guard.fail("user", "127.0.0.1")
+	wait := guard.retryAfter("user", "127.0.0.1")
+	require.True(t, wait > 0 && wait <= time.Second)
+
+	// The backoff applies to the user name from any IP and to the IP for any user name
+	require.NotZero(t, guard.retryAfter("user", "10.0.0.1"))
+	require.NotZero(t, guard.retryAfter("other", "127.0.0.1"))
+	require.Zero(t, guard.retryAfter("other", "10.0.0.1"))
+
+	guard.fail("user", "127.0.0.1")
+	guard.fail("user", "127.0.0.1")
+	require.Greater(t, guard.retryAfter("user", "10.0.0.1"), 59*time.Minute)
+
+	lockouts := guard.lockouts()
+	require.Len(t, lockouts, 2)
+	require.Equal(t, lockoutKindUser, lockouts[0].Kind)
+	require.Equal(t, "user", lockouts[0].Key)
+	require.Equal(t, 3, lockouts[0].Failures)
+	require.True(t, lockouts[0].IsLocked)
+	require.Equal(t, lockoutKindIP, lockouts[1].Kind)
+	require.False(t, lockouts[1].IsLocked)
+
+	require.True(t, guard.unlock("user"))
+	require.False(t, guard.unlock("user"))
+	require.Zero(t, guard.retryAfter("user", "10.0.0.1"))
+}
+
+func TestLoginGuardLockoutPerIP(t *testing.T) {
+	guard := newTestLoginGuard()
+
+	// Spreading the failures over user names does not get around the IP limit
+	for _, userName := range []string{"a", "b", "c", "d", "e"} {
+		guard.fail(userName, "127.0.0.1")
+	}
+
+	require.Greater(t, guard.retryAfter("f", "127.0.0.1"), 59*time.Minute)
+	require.Zero(t, guard.retryAfter("f", "10.0.0.1"))
+}
+
+func TestLoginGuardSucceed(t *testing.T) {
+	guard := newTestLoginGuard()
+
+	guard.fail("user", "127.0.0.1")
+	guard.fail("user", "127.0.0.1")
+	guard.succeed("user")
+
+	require.Zero(t, guard.retryAfter("user", "10.0.0.1"))
+	require.NotZero(t, guard.retryAfter("user", "127.0.0.1"))
+}
+
+func TestLoginGuardPrune(t *testing.T) {
+	guard := newTestLoginGuard()
+	guard.lockoutDuration = 0
+	guard.baseBackoff = 0
+
+	guard.fail("user", "127.0.0.1")
+	time.Sleep(time.Millisecond)
+	guard.prune()
+
+	require.Empty(t, guard.users)
+	require.Empty(t, guard.ips)
+	require.Empty(t, guard.lockouts())
+}
+
+func TestLoginUserLockoutAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, password := randomUser(t)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+		Times(3).
+		Return(user, nil)
+
+	server := newTestServer(t, store)
+	server.loginGuard = newTestLoginGuard()
+	server.loginGuard.baseBackoff = 0
+
+	login := func(password string) *httptest.ResponseRecorder {
+		json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+		data, err := json.Marshal(gin.H{
+			"user_name": user.UserName,
+			"password":  password,
+		})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/api/users/login", bytes.NewReader(data))
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i := 0; i < 3; i++ {
+		recorder := login("incorrect")
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	}
+
+	// Even the right password is refused while locked out, without reaching the DB
+	recorder := login(password)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "3600", recorder.Header().Get("Retry-After"))
+}
+
+func TestListLoginLockoutsAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var lockouts []loginLockoutResponse
+				err := json.NewDecoder(recorder.Body).Decode(&lockouts)
+				require.NoError(t, err)
+				require.Len(t, lockouts, 2)
+				require.Equal(t, "user", lockouts[0].Key)
+				require.Equal(t, "127.0.0.1", lockouts[1].Key)
+			},
+		},
+		{
+			name: "NotAdmin",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", util.UserRole, time.Minute)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+			server.loginGuard = newTestLoginGuard()
+			server.loginGuard.fail("user", "127.0.0.1")
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/api/users/lockouts", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestUnlockUserAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		userName      string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkResponse func(recorder *httptest.ResponseRecorder, guard *loginGuard)
+	}{
+		{
+			name:     "OK",
+			userName: "user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, guard *loginGuard) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Zero(t, guard.retryAfter("user", "10.0.0.1"))
+			},
+		},
+		{
+			name:     "NotFound",
+			userName: "other",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, guard *loginGuard) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "NotAdmin",
+			userName: "user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", util.ModeratorRole, time.Minute)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, guard *loginGuard) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.NotZero(t, guard.retryAfter("user", "10.0.0.1"))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+			server.loginGuard = newTestLoginGuard()
+			server.loginGuard.fail("user", "127.0.0.1")
+
+			recorder := httptest.NewRecorder()
+			url := "/api/users/" + tc.userName + "/lockout"
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, server.loginGuard)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/mail"
 	"github.com/CM-IV/mef-api/util"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -13,8 +14,11 @@ import (
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:     util.RandomString(32),
+		AccessTokenDuration:   time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		EmailSender:           mail.MemorySenderKind,
+		TwoFactorSymmetricKey: util.RandomString(32),
 	}
 
 	server, err := NewServer(config, store)
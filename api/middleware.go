@@ -6,17 +6,23 @@ import (
 	"net/http"
 	"strings"
 
+	db "github.com/CM-IV/mef-api/db/sqlc"
 	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
 	"github.com/gin-gonic/gin"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
 	authorizationPayloadKey = "authorization_payload"
+	authorizationTypeKey    = "authorization_type"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// authMiddleware accepts an access token with the bearer type or a personal API key with the ApiKey type,
+// both end up as the same payload for the handlers
+func authMiddleware(tokenMaker token.Maker, revocations *revocationList, generations *generationList, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 
@@ -33,21 +39,93 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			return
 		}
 
+		var payload *token.Payload
+		var err error
+
 		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
+		switch authorizationType {
+		case authorizationTypeBearer:
+			accessToken := fields[1]
+			payload, err = tokenMaker.VerifyToken(accessToken)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+			if payload.HasScope(util.RefreshScope) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errNotAccessToken))
+				return
+			}
+		case authorizationTypeAPIKey:
+			payload, err = verifyAPIKey(ctx, store, fields[1])
+			if err != nil {
+				if errors.Is(err, errInvalidAPIKey) || errors.Is(err, errExpiredAPIKey) || errors.Is(err, errStaleAPIKey) {
+					ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+					return
+				}
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+		default:
 			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
-		if err != nil {
+		if revocations.isRevoked(payload.ID) {
+			err := errors.New("token has been revoked")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		if generations.isStale(payload.UserName, payload.Generation) {
+			err := errors.New("token has been invalidated")
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
 		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Set(authorizationTypeKey, authorizationType)
+		ctx.Next()
+	}
+}
+
+// requireRole only lets through authenticated users that have one of the given roles
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		if !hasRole(authPayload, roles...) {
+			err := fmt.Errorf("role %s is not allowed to access this resource", authPayload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func hasRole(payload *token.Payload, roles ...string) bool {
+	for _, role := range roles {
+		if payload.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// requireScopes only lets through tokens that were issued for all of the given scopes
+func requireScopes(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		for _, scope := range scopes {
+			if !authPayload.HasScope(scope) {
+				err := fmt.Errorf("token is missing the %s scope", scope)
+				ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+
 		ctx.Next()
 	}
 }
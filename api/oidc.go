@@ -0,0 +1,467 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/oidc"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	//Cookie that ties the callback to the browser that started the login
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/api/auth/oidc"
+	//Number of random bytes in the state, nonce and code verifier of a login
+	oidcSecretSize = 32
+	//Requests to a provider give up after this long
+	oidcRequestTimeout = 10 * time.Second
+	//User names of new users are cut to this length before a suffix is added
+	oidcUserNameLength = 20
+	//Number of user names tried for a new user before giving up
+	oidcUserNameAttempts = 3
+)
+
+var (
+	errUnknownOIDCProvider  = errors.New("unknown login provider")
+	errInvalidOIDCState     = errors.New("invalid or expired login state")
+	errOIDCEmailNotVerified = errors.New("the provider has not verified the email address")
+	errOIDCEmailTaken       = errors.New("an account with this email address already exists, log in with your password and link the provider from your account")
+	errOIDCIdentityTaken    = errors.New("the identity is already linked to an account")
+)
+
+//newOIDCProviders creates the providers of OIDC_PROVIDERS by name
+func newOIDCProviders(config util.Config) (map[string]*oidc.Provider, error) {
+	configs, err := oidc.ParseConfigs(config.OIDCProviders)
+	if err != nil {
+		return nil, err
+	}
+
+	linkByEmail := map[string]bool{}
+	for _, name := range strings.Split(config.OIDCLinkByEmail, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			linkByEmail[name] = true
+		}
+	}
+
+	client := &http.Client{Timeout: oidcRequestTimeout}
+
+	providers := make(map[string]*oidc.Provider, len(configs))
+	for _, providerConfig := range configs {
+		providerConfig.LinkByEmail = linkByEmail[providerConfig.Name]
+		delete(linkByEmail, providerConfig.Name)
+		providers[providerConfig.Name] = oidc.NewProvider(providerConfig, client)
+	}
+
+	for name := range linkByEmail {
+		return nil, fmt.Errorf("OIDC_LINK_BY_EMAIL names unknown OIDC provider %q", name)
+	}
+
+	return providers, nil
+}
+
+// A login that was sent to a provider and has not come back yet
+type oidcLogin struct {
+	provider     string
+	nonce        string
+	codeVerifier string
+	//User the identity gets linked to, empty for a login
+	linkUser  string
+	expiresAt time.Time
+}
+
+// oidcLoginStore keeps started logins in memory by state until their callback.
+// A state can only be taken once, expired logins are dropped on every new one.
+type oidcLoginStore struct {
+	duration time.Duration
+
+	mu     sync.Mutex
+	logins map[string]oidcLogin
+}
+
+func newOIDCLoginStore(config util.Config) *oidcLoginStore {
+	return &oidcLoginStore{
+		duration: config.OIDCStateDuration,
+		logins:   make(map[string]oidcLogin),
+	}
+}
+
+func (store *oidcLoginStore) put(state string, login oidcLogin) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	for s, l := range store.logins {
+		if now.After(l.expiresAt) {
+			delete(store.logins, s)
+		}
+	}
+
+	login.expiresAt = now.Add(store.duration)
+	store.logins[state] = login
+}
+
+func (store *oidcLoginStore) take(state string) (oidcLogin, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	login, ok := store.logins[state]
+	delete(store.logins, state)
+
+	if !ok || time.Now().After(login.expiresAt) {
+		return oidcLogin{}, false
+	}
+	return login, true
+}
+
+type oidcProviderRequest struct {
+	Provider string `uri:"provider" binding:"required,alphanum"`
+}
+
+//startOIDCLogin sends the browser to the login page of the provider.
+//The state, nonce and PKCE code verifier of the login stay on the server.
+func (server *Server) startOIDCLogin(ctx *gin.Context) {
+	var req oidcProviderRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	provider, ok := server.oidcProviders[req.Provider]
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errorResponse(errUnknownOIDCProvider))
+		return
+	}
+
+	authURL, ok := server.beginOIDCLogin(ctx, provider, "")
+	if !ok {
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+type linkOIDCIdentityResponse struct {
+	AuthURL string `json:"auth_url"`
+}
+
+//startOIDCLink starts a login at the provider that links the identity to the authenticated user.
+//The access token cannot come along on a redirect, so the client sends the browser to the returned URL.
+func (server *Server) startOIDCLink(ctx *gin.Context) {
+	var req oidcProviderRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	provider, ok := server.oidcProviders[req.Provider]
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errorResponse(errUnknownOIDCProvider))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	authURL, ok := server.beginOIDCLogin(ctx, provider, authPayload.UserName)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, linkOIDCIdentityResponse{AuthURL: authURL})
+}
+
+//beginOIDCLogin keeps a new login and sets its state cookie, it returns the URL of the login page of the provider
+func (server *Server) beginOIDCLogin(ctx *gin.Context, provider *oidc.Provider, linkUser string) (string, bool) {
+	secrets := make([]string, 3)
+	for i := range secrets {
+		secret, err := util.RandomSecret(oidcSecretSize)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return "", false
+		}
+		secrets[i] = secret
+	}
+	state, nonce, codeVerifier := secrets[0], secrets[1], secrets[2]
+
+	authURL, err := provider.AuthCodeURL(
+		ctx.Request.Context(),
+		server.oidcRedirectURL(provider),
+		state,
+		nonce,
+		oidc.CodeChallenge(codeVerifier),
+	)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return "", false
+	}
+
+	server.oidcLogins.put(state, oidcLogin{
+		provider:     provider.Name(),
+		nonce:        nonce,
+		codeVerifier: codeVerifier,
+		linkUser:     linkUser,
+	})
+
+	server.setOIDCStateCookie(ctx, state, int(server.config.OIDCStateDuration.Seconds()))
+	return authURL, true
+}
+
+type oidcCallbackRequest struct {
+	State            string `form:"state" binding:"required"`
+	Code             string `form:"code"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+//finishOIDCLogin is where the provider sends the browser back to.
+//The user of the identity logs in like with a password, two-factor authentication included.
+func (server *Server) finishOIDCLogin(ctx *gin.Context) {
+	var uri oidcProviderRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req oidcCallbackRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	provider, ok := server.oidcProviders[uri.Provider]
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errorResponse(errUnknownOIDCProvider))
+		return
+	}
+
+	// The state is used up even when the login fails from here on
+	login, ok := server.oidcLogins.take(req.State)
+	cookie, err := ctx.Cookie(oidcStateCookie)
+	server.setOIDCStateCookie(ctx, "", -1)
+
+	if !ok || err != nil || cookie != req.State || login.provider != provider.Name() {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidOIDCState))
+		return
+	}
+
+	if req.Error != "" {
+		err := fmt.Errorf("login was not completed at the provider: %s %s", req.Error, req.ErrorDescription)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if req.Code == "" {
+		err := errors.New("callback has no code")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	idToken, err := provider.Exchange(ctx.Request.Context(), server.oidcRedirectURL(provider), req.Code, login.codeVerifier)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(ctx.Request.Context(), idToken, login.nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	if login.linkUser != "" {
+		server.linkOIDCIdentity(ctx, provider.Name(), login.linkUser, claims)
+		return
+	}
+
+	user, ok := server.getOIDCUser(ctx, provider, claims)
+	if !ok {
+		return
+	}
+
+	if user.IsTotpEnabled {
+		server.requireTwoFactor(ctx, user)
+		return
+	}
+
+	server.startSession(ctx, user)
+}
+
+//linkOIDCIdentity links the identity to the user that started the login
+func (server *Server) linkOIDCIdentity(ctx *gin.Context, provider string, userName string, claims *oidc.Claims) {
+	identity, err := server.store.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		UserName: userName,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusConflict, errorResponse(errOIDCIdentityTaken))
+				return
+			case "foreign_key_violation":
+				//The user was deleted while at the provider
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, identity)
+}
+
+//getOIDCUser finds the user linked to the identity, links it to the user with the same email address
+//or creates a new user. Email addresses only count when both sides have verified them and the provider
+//is trusted to link by them, otherwise whoever claims an address could take over the account of its owner.
+//Admins and moderators always have to link their identities themselves.
+func (server *Server) getOIDCUser(ctx *gin.Context, provider *oidc.Provider, claims *oidc.Claims) (db.User, bool) {
+	user, err := server.store.GetUserByIdentity(ctx, db.GetUserByIdentityParams{
+		Provider: provider.Name(),
+		Subject:  claims.Subject,
+	})
+	if err == nil {
+		return user, true
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		ctx.JSON(http.StatusForbidden, errorResponse(errOIDCEmailNotVerified))
+		return db.User{}, false
+	}
+
+	user, err = server.store.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return server.createOIDCUser(ctx, provider.Name(), claims)
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+
+	if !provider.LinkByEmail() || !user.IsEmailVerified || user.Role != util.UserRole {
+		ctx.JSON(http.StatusConflict, errorResponse(errOIDCEmailTaken))
+		return db.User{}, false
+	}
+
+	_, err = server.store.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		UserName: user.UserName,
+		Provider: provider.Name(),
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+
+	return user, true
+}
+
+//createOIDCUser signs up the user of an identity. Their password is random,
+//they can set one with the forgotten password flow if they want to.
+func (server *Server) createOIDCUser(ctx *gin.Context, provider string, claims *oidc.Claims) (db.User, bool) {
+	password, err := util.RandomSecret(oidcSecretSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+
+	hashedPassword, err := util.HashPassword(password)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+
+	baseName := oidcUserName(claims)
+	userName := baseName
+
+	fullName := claims.Name
+	if fullName == "" {
+		fullName = userName
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := server.store.CreateUserWithIdentityTx(ctx, db.CreateUserWithIdentityTxParams{
+			CreateUserParams: db.CreateUserParams{
+				UserName:       userName,
+				HashedPassword: hashedPassword,
+				FullName:       fullName,
+				Email:          claims.Email,
+			},
+			Provider: provider,
+			Subject:  claims.Subject,
+		})
+		if err == nil {
+			return result.User, true
+		}
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			// Someone has the user name already, try again with a number after it
+			if pqErr.Constraint == "users_user_name_key" && attempt < oidcUserNameAttempts {
+				userName = fmt.Sprintf("%s%d", baseName, util.RandomInt(1000, 9999))
+				continue
+			}
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return db.User{}, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+}
+
+//oidcUserName picks the user name of a new user from their name at the provider, keeping only letters and digits
+func oidcUserName(claims *oidc.Claims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	var sb strings.Builder
+	for _, r := range name {
+		if sb.Len() == oidcUserNameLength {
+			break
+		}
+		if r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			sb.WriteRune(r)
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "user"
+	}
+	return sb.String()
+}
+
+func (server *Server) oidcRedirectURL(provider *oidc.Provider) string {
+	return fmt.Sprintf("%s/api/auth/oidc/%s/callback", server.config.AppBaseURL, provider.Name())
+}
+
+//The cookie has to come along on the redirect back from the provider, so it is SameSite=Lax
+func (server *Server) setOIDCStateCookie(ctx *gin.Context, state string, maxAge int) {
+	secure := strings.HasPrefix(server.config.AppBaseURL, "https://")
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, state, maxAge, oidcCookiePath, "", secure, true)
+}
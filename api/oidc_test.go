@@ -0,0 +1,762 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/mail"
+	"github.com/CM-IV/mef-api/oidc"
+	"github.com/CM-IV/mef-api/oidc/oidctest"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/golang/mock/gomock"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func newOIDCTestServer(t *testing.T, store db.Store, providers ...string) *Server {
+	config := util.Config{
+		TokenSymmetricKey:      util.RandomString(32),
+		AccessTokenDuration:    time.Minute,
+		RefreshTokenDuration:   time.Hour,
+		TwoFactorTokenDuration: time.Minute,
+		AppBaseURL:             "http://localhost:8080",
+		OIDCProviders:          strings.Join(providers, ","),
+		OIDCStateDuration:      time.Minute,
+		EmailSender:            mail.MemorySenderKind,
+		TwoFactorSymmetricKey:  util.RandomString(32),
+	}
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	return server
+}
+
+// startOIDC starts a login at the provider and approves it there.
+// It returns the state cookie and the callback the provider redirects to.
+func startOIDC(t *testing.T, server *Server, provider string) (*http.Cookie, string) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/api/auth/oidc/"+provider+"/start", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusFound, recorder.Code)
+
+	return approveOIDC(t, recorder, provider, recorder.Header().Get("Location"))
+}
+
+// startOIDCLink starts linking an identity to the user like startOIDC starts a login
+func startOIDCLink(t *testing.T, server *Server, provider string, userName string) (*http.Cookie, string) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/api/users/me/identities/"+provider, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, userName, util.UserRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	var rsp linkOIDCIdentityResponse
+	err = json.NewDecoder(recorder.Body).Decode(&rsp)
+	require.NoError(t, err)
+
+	return approveOIDC(t, recorder, provider, rsp.AuthURL)
+}
+
+// approveOIDC follows the URL of the login page to the provider, which approves the login right away
+func approveOIDC(t *testing.T, recorder *httptest.ResponseRecorder, provider string, authURL string) (*http.Cookie, string) {
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, oidcStateCookie, cookies[0].Name)
+	require.True(t, cookies[0].HttpOnly)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	rsp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusFound, rsp.StatusCode)
+
+	callback, err := rsp.Location()
+	require.NoError(t, err)
+	require.Equal(t, "/api/auth/oidc/"+provider+"/callback", callback.Path)
+	require.Equal(t, cookies[0].Value, callback.Query().Get("state"))
+
+	return cookies[0], callback.RequestURI()
+}
+
+func finishOIDC(t *testing.T, server *Server, cookie *http.Cookie, callback string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, callback, nil)
+	require.NoError(t, err)
+
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestStartOIDCLoginAPI(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newOIDCTestServer(t, mockdb.NewMockStore(ctrl), fake.Spec("fake"))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/api/auth/oidc/fake/start", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusFound, recorder.Code)
+
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(location.String(), fake.Issuer()+"/authorize"))
+
+	query := location.Query()
+	require.Equal(t, fake.ClientID, query.Get("client_id"))
+	require.Equal(t, "http://localhost:8080/api/auth/oidc/fake/callback", query.Get("redirect_uri"))
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.NotEmpty(t, query.Get("code_challenge"))
+	require.NotEmpty(t, query.Get("nonce"))
+
+	// The verifier never leaves the server
+	login, ok := server.oidcLogins.take(query.Get("state"))
+	require.True(t, ok)
+	require.Equal(t, oidc.CodeChallenge(login.codeVerifier), query.Get("code_challenge"))
+	require.Equal(t, login.nonce, query.Get("nonce"))
+
+	// Unknown providers
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/api/auth/oidc/other/start", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	requireBodyError(t, recorder.Body, errUnknownOIDCProvider)
+}
+
+func TestFinishOIDCLoginAPI(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+
+	identity := oidctest.User{
+		Subject:           "248289761001",
+		Email:             user.Email,
+		EmailVerified:     true,
+		Name:              "Jane Doe",
+		PreferredUsername: "jane.doe",
+	}
+
+	testCases := []struct {
+		name          string
+		identity      oidctest.User
+		linkByEmail   bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "LinkedIdentity",
+			identity: identity,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Eq(db.GetUserByIdentityParams{Provider: "fake", Subject: identity.Subject})).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp loginUserResponse
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.Equal(t, user.UserName, rsp.User.UserName)
+			},
+		},
+		{
+			name:        "LinkByEmail",
+			identity:    identity,
+			linkByEmail: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateUserIdentity(gomock.Any(), gomock.Eq(db.CreateUserIdentityParams{
+						UserName: user.UserName,
+						Provider: "fake",
+						Subject:  identity.Subject,
+						Email:    user.Email,
+					})).
+					Times(1)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "NotLinkedByDefault",
+			identity: identity,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateUserIdentity(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyError(t, recorder.Body, errOIDCEmailTaken)
+			},
+		},
+		{
+			name:        "PrivilegedRole",
+			identity:    identity,
+			linkByEmail: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				admin := user
+				admin.Role = util.AdminRole
+
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(admin, nil)
+				store.EXPECT().
+					CreateUserIdentity(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyError(t, recorder.Body, errOIDCEmailTaken)
+			},
+		},
+		{
+			name:        "LocalEmailNotVerified",
+			identity:    identity,
+			linkByEmail: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				unverified := user
+				unverified.IsEmailVerified = false
+
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(unverified, nil)
+				store.EXPECT().
+					CreateUserIdentity(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyError(t, recorder.Body, errOIDCEmailTaken)
+			},
+		},
+		{
+			name: "ProviderEmailNotVerified",
+			identity: oidctest.User{
+				Subject:       identity.Subject,
+				Email:         user.Email,
+				EmailVerified: false,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyError(t, recorder.Body, errOIDCEmailNotVerified)
+			},
+		},
+		{
+			name:     "NewUser",
+			identity: identity,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateUserWithIdentityTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateUserWithIdentityTxParams) (db.CreateUserWithIdentityTxResult, error) {
+						require.Equal(t, "janedoe", arg.UserName)
+						require.Equal(t, identity.Name, arg.FullName)
+						require.Equal(t, identity.Email, arg.Email)
+						require.Equal(t, "fake", arg.Provider)
+						require.Equal(t, identity.Subject, arg.Subject)
+						require.NotEmpty(t, arg.HashedPassword)
+
+						return db.CreateUserWithIdentityTxResult{
+							User: db.User{
+								UserName:        arg.UserName,
+								FullName:        arg.FullName,
+								Email:           arg.Email,
+								Role:            util.UserRole,
+								IsEmailVerified: true,
+							},
+						}, nil
+					})
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp loginUserResponse
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.Equal(t, "janedoe", rsp.User.UserName)
+				require.True(t, rsp.User.IsEmailVerified)
+			},
+		},
+		{
+			name:     "NewUserNameTaken",
+			identity: identity,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				gomock.InOrder(
+					store.EXPECT().
+						CreateUserWithIdentityTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.CreateUserWithIdentityTxResult{}, &pq.Error{Code: "23505", Constraint: "users_user_name_key"}),
+					store.EXPECT().
+						CreateUserWithIdentityTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ interface{}, arg db.CreateUserWithIdentityTxParams) (db.CreateUserWithIdentityTxResult, error) {
+							require.True(t, strings.HasPrefix(arg.UserName, "janedoe"))
+							require.Len(t, arg.UserName, len("janedoe")+4)
+							return db.CreateUserWithIdentityTxResult{User: db.User{UserName: arg.UserName}}, nil
+						}),
+				)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "TwoFactor",
+			identity: identity,
+			buildStubs: func(store *mockdb.MockStore) {
+				twoFactorUser := user
+				twoFactorUser.IsTotpEnabled = true
+
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(twoFactorUser, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp twoFactorRequiredResponse
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.TwoFactorRequired)
+				require.NotEmpty(t, rsp.TwoFactorToken)
+			},
+		},
+		{
+			name:     "InternalError",
+			identity: identity,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newOIDCTestServer(t, store, fake.Spec("fake"))
+			if tc.linkByEmail {
+				server.config.OIDCLinkByEmail = "fake"
+				providers, err := newOIDCProviders(server.config)
+				require.NoError(t, err)
+				server.oidcProviders = providers
+			}
+			fake.SetUser(tc.identity)
+
+			cookie, callback := startOIDC(t, server, "fake")
+			recorder := finishOIDC(t, server, cookie, callback)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestFinishOIDCLoginStateAPI(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+
+	testCases := []struct {
+		name   string
+		finish func(t *testing.T, server *Server) *httptest.ResponseRecorder
+		code   int
+	}{
+		{
+			name: "NoCookie",
+			finish: func(t *testing.T, server *Server) *httptest.ResponseRecorder {
+				_, callback := startOIDC(t, server, "fake")
+				return finishOIDC(t, server, nil, callback)
+			},
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "CookieOfOtherLogin",
+			finish: func(t *testing.T, server *Server) *httptest.ResponseRecorder {
+				cookie, _ := startOIDC(t, server, "fake")
+				_, callback := startOIDC(t, server, "fake")
+				return finishOIDC(t, server, cookie, callback)
+			},
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "UnknownState",
+			finish: func(t *testing.T, server *Server) *httptest.ResponseRecorder {
+				cookie := &http.Cookie{Name: oidcStateCookie, Value: "forged"}
+				return finishOIDC(t, server, cookie, "/api/auth/oidc/fake/callback?code=code-1&state=forged")
+			},
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "StateUsedTwice",
+			finish: func(t *testing.T, server *Server) *httptest.ResponseRecorder {
+				cookie, callback := startOIDC(t, server, "fake")
+				u, err := url.Parse(callback)
+				require.NoError(t, err)
+
+				// The provider reports an error, which uses up the state
+				query := u.Query()
+				query.Del("code")
+				query.Set("error", "access_denied")
+				recorder := finishOIDC(t, server, cookie, u.Path+"?"+query.Encode())
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+				return finishOIDC(t, server, cookie, callback)
+			},
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "OtherProvider",
+			finish: func(t *testing.T, server *Server) *httptest.ResponseRecorder {
+				cookie, callback := startOIDC(t, server, "fake")
+				callback = strings.Replace(callback, "/fake/", "/other/", 1)
+				return finishOIDC(t, server, cookie, callback)
+			},
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "ProviderError",
+			finish: func(t *testing.T, server *Server) *httptest.ResponseRecorder {
+				cookie, callback := startOIDC(t, server, "fake")
+				u, err := url.Parse(callback)
+				require.NoError(t, err)
+
+				query := u.Query()
+				query.Del("code")
+				query.Set("error", "access_denied")
+				return finishOIDC(t, server, cookie, u.Path+"?"+query.Encode())
+			},
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "WrongCode",
+			finish: func(t *testing.T, server *Server) *httptest.ResponseRecorder {
+				cookie, callback := startOIDC(t, server, "fake")
+				u, err := url.Parse(callback)
+				require.NoError(t, err)
+
+				query := u.Query()
+				query.Set("code", "forged")
+				return finishOIDC(t, server, cookie, u.Path+"?"+query.Encode())
+			},
+			code: http.StatusBadGateway,
+		},
+		{
+			name: "NoState",
+			finish: func(t *testing.T, server *Server) *httptest.ResponseRecorder {
+				return finishOIDC(t, server, nil, "/api/auth/oidc/fake/callback?code=code-1")
+			},
+			code: http.StatusBadRequest,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUserByIdentity(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			server := newOIDCTestServer(t, store, fake.Spec("fake"), fake.Spec("other"))
+
+			recorder := tc.finish(t, server)
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
+}
+
+func TestLinkOIDCIdentityAPI(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	user, _ := randomUser(t)
+
+	identity := oidctest.User{
+		Subject:       "248289761001",
+		Email:         util.RandomEmail(),
+		EmailVerified: false,
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserIdentity(gomock.Any(), gomock.Eq(db.CreateUserIdentityParams{
+						UserName: user.UserName,
+						Provider: "fake",
+						Subject:  identity.Subject,
+						Email:    identity.Email,
+					})).
+					Times(1).
+					Return(db.UserIdentity{UserName: user.UserName, Provider: "fake", Subject: identity.Subject}, nil)
+				store.EXPECT().
+					GetUserByIdentity(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp db.UserIdentity
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.Equal(t, user.UserName, rsp.UserName)
+				require.Equal(t, "fake", rsp.Provider)
+			},
+		},
+		{
+			name: "IdentityTaken",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserIdentity{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyError(t, recorder.Body, errOIDCIdentityTaken)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserIdentity(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserIdentity{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newOIDCTestServer(t, store, fake.Spec("fake"))
+			fake.SetUser(identity)
+
+			cookie, callback := startOIDCLink(t, server, "fake", user.UserName)
+			recorder := finishOIDC(t, server, cookie, callback)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestStartOIDCLinkNoAuthorization(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newOIDCTestServer(t, mockdb.NewMockStore(ctrl), fake.Spec("fake"))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/api/users/me/identities/fake", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Empty(t, recorder.Result().Cookies())
+}
+
+func TestNewOIDCProvidersLinkByEmail(t *testing.T) {
+	config := util.Config{
+		OIDCProviders:   "gitlab|https://gitlab.com|id|secret,google|https://accounts.google.com|id2|secret2",
+		OIDCLinkByEmail: "google",
+	}
+
+	providers, err := newOIDCProviders(config)
+	require.NoError(t, err)
+	require.True(t, providers["google"].LinkByEmail())
+	require.False(t, providers["gitlab"].LinkByEmail())
+
+	config.OIDCLinkByEmail = "google,other"
+	_, err = newOIDCProviders(config)
+	require.Error(t, err)
+}
+
+func TestOIDCUserName(t *testing.T) {
+	testCases := []struct {
+		claims oidc.Claims
+		name   string
+	}{
+		{claims: oidc.Claims{PreferredUsername: "jane.doe", Email: "jd@example.com"}, name: "janedoe"},
+		{claims: oidc.Claims{Email: "jane_doe+forum@example.com"}, name: "janedoeforum"},
+		{claims: oidc.Claims{PreferredUsername: "żółć", Email: "ąę@example.com"}, name: "user"},
+		{claims: oidc.Claims{PreferredUsername: strings.Repeat("a", 40)}, name: strings.Repeat("a", oidcUserNameLength)},
+		{claims: oidc.Claims{}, name: "user"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.name, oidcUserName(&tc.claims))
+	}
+}
+
+func TestOIDCLoginStore(t *testing.T) {
+	store := newOIDCLoginStore(util.Config{OIDCStateDuration: time.Minute})
+
+	store.put("state", oidcLogin{provider: "fake", nonce: "nonce"})
+
+	login, ok := store.take("state")
+	require.True(t, ok)
+	require.Equal(t, "fake", login.provider)
+
+	_, ok = store.take("state")
+	require.False(t, ok)
+
+	expired := newOIDCLoginStore(util.Config{OIDCStateDuration: -time.Second})
+	expired.put("state", oidcLogin{provider: "fake"})
+
+	_, ok = expired.take("state")
+	require.False(t, ok)
+
+}
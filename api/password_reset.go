@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+)
+
+//Number of random bytes in a password reset token
+const passwordResetTokenSize = 32
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required,max=64"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+//forgotPassword mails a reset token to the owner of the address.
+//It answers 202 whether or not the address belongs to an account so accounts cannot be enumerated.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.Status(http.StatusAccepted)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The reset is stored and mailed in the background, so a known address
+	// answers as fast as an unknown one and the timing gives nothing away
+	server.tasks.Add(1)
+	go func() {
+		defer server.tasks.Done()
+
+		if err := server.sendPasswordReset(context.Background(), user); err != nil {
+			log.Println("cannot send password reset email:", err)
+		}
+	}()
+
+	ctx.Status(http.StatusAccepted)
+}
+
+//resetPassword sets a new password with a reset token, tokens issued before the reset stop working
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      util.HashToken(req.Token),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := errors.New("invalid or expired reset token")
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.generations.set(result.User.UserName, result.User.TokenGeneration)
+
+	ctx.Status(http.StatusNoContent)
+}
+
+//sendPasswordReset creates a reset token for the user and mails it to them
+func (server *Server) sendPasswordReset(ctx context.Context, user db.User) error {
+	resetToken, err := util.RandomSecret(passwordResetTokenSize)
+	if err != nil {
+		return err
+	}
+
+	// Only the hash is stored, a leaked table cannot be used to reset passwords
+	reset, err := server.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		UserName:  user.UserName,
+		TokenHash: util.HashToken(resetToken),
+		ExpiredAt: time.Now().Add(server.config.PasswordResetDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	subject := "Reset your password"
+	content := fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password of your account. If it was you, use the token below:\n\n%s\n\nThe token expires at %s. If you did not ask for a reset you can ignore this email.\n",
+		user.FullName,
+		resetToken,
+		reset.ExpiredAt.Format(time.RFC1123),
+	)
+
+	return server.mailer.SendEmail(subject, content, []string{user.Email})
+}
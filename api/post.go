@@ -2,20 +2,25 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"math"
 	"net/http"
+	"time"
 
 	db "github.com/CM-IV/mef-api/db/sqlc"
 	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
 
 type createPostRequest struct {
-	Image    string `json:"image" binding:"required"`
-	Title    string `json:"title" binding:"required"`
-	Subtitle string `json:"subtitle" binding:"required"`
-	Content  string `json:"content" binding:"required"`
+	Image      string   `json:"image" binding:"required"`
+	Title      string   `json:"title" binding:"required"`
+	Subtitle   string   `json:"subtitle" binding:"required"`
+	Content    string   `json:"content" binding:"required"`
+	CategoryID int64    `json:"category_id" binding:"omitempty,min=1"`
+	Tags       []string `json:"tags" binding:"omitempty,max=10,dive,slug,max=32"`
 }
 
 type getPostRequest struct {
@@ -26,11 +31,56 @@ type deletePostRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
+//Either page_id and page_size, or limit with an optional after cursor
 type listPostRequest struct {
+	PageID   int32    `form:"page_id" binding:"omitempty,min=1"`
+	PageSize int32    `form:"page_size" binding:"omitempty,min=5,max=15"`
+	After    string   `form:"after"`
+	Limit    int32    `form:"limit" binding:"omitempty,min=5,max=15"`
+	Category string   `form:"category" binding:"omitempty,slug"`
+	Tags     []string `form:"tag" binding:"omitempty,max=10,dive,slug,max=32"`
+	Match    string   `form:"match" binding:"omitempty,oneof=any all"`
+
+	Sort          string    `form:"sort" binding:"omitempty,oneof=newest oldest title most-commented"`
+	Order         string    `form:"order" binding:"omitempty,oneof=asc desc"`
+	Owner         string    `form:"owner" binding:"omitempty,alphanum"`
+	CreatedAfter  time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00" binding:"omitempty,gtfield=CreatedAfter"`
+}
+
+//Titles are unique among the posts of an owner that are not in the trash
+var errPostTitleTaken = errors.New("the owner already has a post with this title")
+
+type postSort struct {
+	key  string
+	desc bool
+}
+
+//Sort options of listPost, mapped to the ListPosts sort key and its natural direction
+var postSorts = map[string]postSort{
+	"newest":         {key: "created_at", desc: true},
+	"oldest":         {key: "created_at", desc: false},
+	"title":          {key: "title", desc: false},
+	"most-commented": {key: "comments", desc: true},
+}
+
+type searchPostRequest struct {
+	Query    string `form:"q" binding:"required,max=256"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=15"`
+}
+
+type listTrashRequest struct {
 	PageID   int32 `form:"page_id" binding:"required,min=1"`
 	PageSize int32 `form:"page_size" binding:"required,min=5,max=15"`
 }
 
+//Post with the names of its tags
+type postResponse struct {
+	db.Post
+	Tags []string `json:"tags"`
+}
+
 type updatePostRequest struct {
 	Content string `json:"content" binding:"required"`
 }
@@ -39,6 +89,13 @@ type updatePostRequestID struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
+type patchPostRequest struct {
+	Image    *string `json:"image" binding:"omitempty,min=1"`
+	Title    *string `json:"title" binding:"omitempty,min=1"`
+	Subtitle *string `json:"subtitle" binding:"omitempty,min=1"`
+	Content  *string `json:"content" binding:"omitempty,min=1"`
+}
+
 func (server *Server) createPost(ctx *gin.Context) {
 
 	var req createPostRequest
@@ -50,16 +107,45 @@ func (server *Server) createPost(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	arg := db.CreatePostParams{
 
-		Owner:    authPayload.UserName,
-		Image:    req.Image,
-		Title:    req.Title,
-		Subtitle: req.Subtitle,
-		Content:  req.Content,
+	user, err := server.store.GetUser(ctx, authPayload.UserName)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	post, err := server.store.CreatePost(ctx, arg)
+	if !user.IsEmailVerified {
+
+		ctx.JSON(http.StatusForbidden, errorResponse(errEmailNotVerified))
+		return
+
+	}
+
+	arg := db.CreatePostWithTagsTxParams{
+
+		CreatePostParams: db.CreatePostParams{
+			Owner:    authPayload.UserName,
+			Image:    req.Image,
+			Title:    req.Title,
+			Subtitle: req.Subtitle,
+			Content:  req.Content,
+		},
+		Tags: uniqueTags(req.Tags),
+	}
+
+	if req.CategoryID != 0 {
+		arg.CategoryID = &req.CategoryID
+	}
+
+	result, err := server.store.CreatePostWithTagsTx(ctx, arg)
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
@@ -74,7 +160,12 @@ func (server *Server) createPost(ctx *gin.Context) {
 
 	}
 
-	ctx.JSON(http.StatusCreated, post)
+	resp := postResponse{Post: result.Post, Tags: []string{}}
+	for _, tag := range result.Tags {
+		resp.Tags = append(resp.Tags, tag.Name)
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
 
 }
 
@@ -102,7 +193,20 @@ func (server *Server) getPost(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, post)
+	tags, err := server.store.ListTagsByPost(ctx, post.ID)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := postResponse{Post: post, Tags: []string{}}
+	for _, tag := range tags {
+		resp.Tags = append(resp.Tags, tag.Name)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
 
 }
 
@@ -118,13 +222,96 @@ func (server *Server) listPost(ctx *gin.Context) {
 
 	}
 
+	cursorMode := req.After != "" || req.Limit != 0
+
+	if cursorMode && (req.PageID != 0 || req.PageSize != 0) {
+
+		err := errors.New("page_id and page_size cannot be combined with after and limit")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if cursorMode && (req.Sort != "" || req.Order != "") {
+
+		err := errors.New("sort and order cannot be combined with after and limit")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if cursorMode && req.Limit == 0 {
+
+		err := errors.New("limit is required when paging with a cursor")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if !cursorMode && (req.PageID == 0 || req.PageSize == 0) {
+
+		err := errors.New("page_id and page_size are required")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
 	args := db.ListPostsParams{
 
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+		Tags:          uniqueTags(req.Tags),
+		MatchAll:      req.Match == "all",
+		Owner:         sql.NullString{String: req.Owner, Valid: req.Owner != ""},
+		CreatedAfter:  sql.NullTime{Time: req.CreatedAfter, Valid: !req.CreatedAfter.IsZero()},
+		CreatedBefore: sql.NullTime{Time: req.CreatedBefore, Valid: !req.CreatedBefore.IsZero()},
+		SortKey:       "id",
+		Limit:         req.PageSize,
+		Offset:        (req.PageID - 1) * req.PageSize,
+	}
+
+	if sort, ok := postSorts[req.Sort]; ok {
+		args.SortKey = sort.key
+		args.SortDesc = sort.desc
+	}
+
+	if req.Order != "" {
+		args.SortDesc = req.Order == "desc"
+	}
+
+	if req.Category != "" {
+
+		category, err := server.store.GetCategoryBySlug(ctx, req.Category)
+
+		if err != nil {
+
+			if err == sql.ErrNoRows {
+
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		args.CategoryID = sql.NullInt64{Int64: category.ID, Valid: true}
+
+	}
+
+	if cursorMode {
+
+		server.listPostsAfter(ctx, req, args)
+		return
+
 	}
 
-	totalRecords, err := server.store.CountPosts(ctx)
+	totalRecords, err := server.store.CountPosts(ctx, db.CountPostsParams{
+		CategoryID:    args.CategoryID,
+		Tags:          args.Tags,
+		MatchAll:      args.MatchAll,
+		Owner:         args.Owner,
+		CreatedAfter:  args.CreatedAfter,
+		CreatedBefore: args.CreatedBefore,
+	})
 
 	if err != nil {
 
@@ -156,6 +343,109 @@ func (server *Server) listPost(ctx *gin.Context) {
 
 }
 
+// listPostsAfter serves the keyset mode of listPost, reusing the filters already resolved in args.
+// One extra row is fetched to know whether another page follows.
+func (server *Server) listPostsAfter(ctx *gin.Context, req listPostRequest, args db.ListPostsParams) {
+
+	arg := db.ListPostsAfterParams{
+
+		CategoryID:    args.CategoryID,
+		Tags:          args.Tags,
+		MatchAll:      args.MatchAll,
+		Owner:         args.Owner,
+		CreatedAfter:  args.CreatedAfter,
+		CreatedBefore: args.CreatedBefore,
+		Limit:         req.Limit + 1,
+	}
+
+	if req.After != "" {
+
+		cursor, err := decodePostCursor(req.After)
+
+		if err != nil {
+
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+
+		}
+
+		arg.AfterCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		arg.AfterID = cursor.ID
+
+	}
+
+	posts, err := server.store.ListPostsAfter(ctx, arg)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var resp struct {
+		NextCursor string    `json:"next_cursor"`
+		Posts      []db.Post `json:"posts"`
+	}
+
+	if len(posts) > int(req.Limit) {
+		posts = posts[:req.Limit]
+		resp.NextCursor = newPostCursor(posts[len(posts)-1]).encode()
+	}
+
+	resp.Posts = posts
+
+	ctx.JSON(http.StatusOK, resp)
+
+}
+
+func (server *Server) searchPosts(ctx *gin.Context) {
+
+	var req searchPostRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	args := db.SearchPostsParams{
+
+		Query:  req.Query,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	totalRecords, err := server.store.CountSearchPosts(ctx, args.Query)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	posts, err := server.store.SearchPosts(ctx, args)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var resp struct {
+		TotalRecords int64               `json:"total_records"`
+		LastPage     int64               `json:"last_page"`
+		Posts        []db.SearchPostsRow `json:"posts"`
+	}
+
+	resp.TotalRecords = totalRecords
+	resp.LastPage = int64(math.Ceil(float64(totalRecords) / float64(args.Limit)))
+	resp.Posts = posts
+
+	ctx.JSON(http.StatusOK, resp)
+
+}
+
 func (server *Server) updatePost(ctx *gin.Context) {
 
 	var req updatePostRequest
@@ -175,13 +465,17 @@ func (server *Server) updatePost(ctx *gin.Context) {
 
 	}
 
-	args := db.UpdatePostParams{
+	if _, ok := server.getOwnedPost(ctx, id.ID); !ok {
+		return
+	}
+
+	args := db.PartialUpdatePostParams{
 
 		ID:      id.ID,
-		Content: req.Content,
+		Content: sql.NullString{String: req.Content, Valid: true},
 	}
 
-	post, err_update := server.store.UpdatePost(ctx, args)
+	result, err_update := server.store.UpdatePostTx(ctx, args)
 
 	if err_update != nil {
 
@@ -195,7 +489,72 @@ func (server *Server) updatePost(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, post)
+	ctx.JSON(http.StatusOK, result.Post)
+
+}
+
+func (server *Server) patchPost(ctx *gin.Context) {
+
+	var req patchPostRequest
+	var id updatePostRequestID
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if req.Image == nil && req.Title == nil && req.Subtitle == nil && req.Content == nil {
+
+		err := errors.New("at least one of image, title, subtitle or content must be provided")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if _, ok := server.getOwnedPost(ctx, id.ID); !ok {
+		return
+	}
+
+	args := db.PartialUpdatePostParams{
+
+		ID:       id.ID,
+		Image:    nullString(req.Image),
+		Title:    nullString(req.Title),
+		Subtitle: nullString(req.Subtitle),
+		Content:  nullString(req.Content),
+	}
+
+	result, err := server.store.UpdatePostTx(ctx, args)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusConflict, errorResponse(errPostTitleTaken))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result.Post)
 
 }
 
@@ -206,9 +565,14 @@ func (server *Server) deletePost(ctx *gin.Context) {
 	if err := ctx.ShouldBindUri(&req); err != nil {
 
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
 
 	}
 
+	if _, ok := server.getOwnedPost(ctx, req.ID); !ok {
+		return
+	}
+
 	del_err := server.store.DeletePost(ctx, req.ID)
 
 	if del_err != nil {
@@ -216,11 +580,170 @@ func (server *Server) deletePost(ctx *gin.Context) {
 		if del_err == sql.ErrNoRows {
 
 			ctx.JSON(http.StatusNotFound, errorResponse(del_err))
+			return
 
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(del_err))
+		return
 	}
 
 	ctx.JSON(http.StatusOK, del_err)
 
 }
+
+func (server *Server) restorePost(ctx *gin.Context) {
+
+	var req getPostRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	post, err := server.store.GetDeletedPost(ctx, req.ID)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !server.checkPostOwner(ctx, post) {
+		return
+	}
+
+	post, err = server.store.RestorePost(ctx, req.ID)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusConflict, errorResponse(errPostTitleTaken))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, post)
+
+}
+
+func (server *Server) listTrash(ctx *gin.Context) {
+
+	var req listTrashRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	args := db.ListDeletedPostsByOwnerParams{
+
+		Owner:  authPayload.UserName,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	posts, err := server.store.ListDeletedPostsByOwner(ctx, args)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, posts)
+
+}
+
+// getOwnedPost fetches the post and checks that it belongs to the authenticated user,
+// moderators and admins may manage any post.
+// The error response is already written when false is returned.
+func (server *Server) getOwnedPost(ctx *gin.Context, id int64) (db.Post, bool) {
+
+	post, err := server.store.GetPost(ctx, id)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return post, false
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return post, false
+	}
+
+	return post, server.checkPostOwner(ctx, post)
+
+}
+
+// checkPostOwner writes a forbidden response unless the post belongs to the authenticated user
+// or the user is a moderator or admin
+func (server *Server) checkPostOwner(ctx *gin.Context, post db.Post) bool {
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if post.Owner != authPayload.UserName && !hasRole(authPayload, util.ModeratorRole, util.AdminRole) {
+
+		err := errors.New("post doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return false
+
+	}
+
+	return true
+
+}
+
+// nullString maps an optional request field to a nullable query param
+func nullString(value *string) sql.NullString {
+
+	if value == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *value, Valid: true}
+
+}
+
+// uniqueTags drops repeated tag names, keeping the first occurrence of each
+func uniqueTags(tags []string) []string {
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	unique := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+
+	return unique
+
+}
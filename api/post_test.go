@@ -40,13 +40,46 @@ func TestGetPostAPI(t *testing.T) {
 					GetPost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
 					Return(post, nil)
+				store.EXPECT().
+					ListTagsByPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return([]db.Tag{{ID: 1, Name: "fees"}, {ID: 2, Name: "xmr"}}, nil)
 
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 
 				//check response
 				require.Equal(t, http.StatusOK, recorder.Code)
-				requireBodyMatchPost(t, recorder.Body, post)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var gotPost postResponse
+				err := json.NewDecoder(recorder.Body).Decode(&gotPost)
+				require.NoError(t, err)
+				require.Equal(t, post.ID, gotPost.ID)
+				require.Equal(t, post.Title, gotPost.Title)
+				require.Equal(t, []string{"fees", "xmr"}, gotPost.Tags)
+
+			},
+		},
+		{
+
+			title:  "TagsInternalError",
+			postID: post.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					ListTagsByPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return([]db.Tag{}, sql.ErrConnDone)
+
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 
 			},
 		},
@@ -148,9 +181,13 @@ func TestDeletePostAPI(t *testing.T) {
 			title:  "OK",
 			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
 				store.EXPECT().
 					DeletePost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
@@ -169,13 +206,16 @@ func TestDeletePostAPI(t *testing.T) {
 			title:  "NotFound",
 			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					DeletePost(gomock.Any(), gomock.Eq(post.ID)).
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
-					Return(sql.ErrNoRows)
+					Return(db.Post{}, sql.ErrNoRows)
+				store.EXPECT().
+					DeletePost(gomock.Any(), gomock.Any()).
+					Times(0)
 
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -189,9 +229,13 @@ func TestDeletePostAPI(t *testing.T) {
 			title:  "InternalError",
 			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
 				store.EXPECT().
 					DeletePost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
@@ -209,13 +253,15 @@ func TestDeletePostAPI(t *testing.T) {
 			title:  "InvalidID",
 			postID: 0,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Any()).
+					Times(0)
 				store.EXPECT().
 					DeletePost(gomock.Any(), gomock.Any()).
-					Times(1).
-					Return(&pq.Error{Code: "22003"})
+					Times(0)
 
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -224,6 +270,53 @@ func TestDeletePostAPI(t *testing.T) {
 
 			},
 		},
+		{
+
+			title:  "Moderator",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "moderator", util.ModeratorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					DeletePost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(nil)
+
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+			},
+		},
+		{
+
+			title:  "UnauthorizedUser",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized", util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					DeletePost(gomock.Any(), gomock.Any()).
+					Times(0)
+
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+
+			},
+		},
 		{
 			title: "NoAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
@@ -292,17 +385,21 @@ func TestUpdatePostAPI(t *testing.T) {
 			},
 			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				arg := db.UpdatePostParams{
+				arg := db.PartialUpdatePostParams{
 					ID:      post.ID,
-					Content: post.Content,
+					Content: sql.NullString{String: post.Content, Valid: true},
 				}
 				store.EXPECT().
-					UpdatePost(gomock.Any(), gomock.Eq(arg)).
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
 					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdatePostTxResult{Post: post}, nil)
 
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -320,17 +417,16 @@ func TestUpdatePostAPI(t *testing.T) {
 			},
 			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				arg := db.UpdatePostParams{
-					ID:      post.ID,
-					Content: post.Content,
-				}
 				store.EXPECT().
-					UpdatePost(gomock.Any(), gomock.Eq(arg)).
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
 					Return(db.Post{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
 
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -347,17 +443,21 @@ func TestUpdatePostAPI(t *testing.T) {
 			},
 			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				arg := db.UpdatePostParams{
+				arg := db.PartialUpdatePostParams{
 					ID:      post.ID,
-					Content: post.Content,
+					Content: sql.NullString{String: post.Content, Valid: true},
 				}
 				store.EXPECT().
-					UpdatePost(gomock.Any(), gomock.Eq(arg)).
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
-					Return(db.Post{}, sql.ErrConnDone)
+					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdatePostTxResult{}, sql.ErrConnDone)
 
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -374,11 +474,11 @@ func TestUpdatePostAPI(t *testing.T) {
 			},
 			postID: 0,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					UpdatePost(gomock.Any(), gomock.Any()).
+					UpdatePostTx(gomock.Any(), gomock.Any()).
 					Times(0)
 
 			},
@@ -396,11 +496,11 @@ func TestUpdatePostAPI(t *testing.T) {
 			},
 			postID: 0,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					UpdatePost(gomock.Any(), gomock.Any()).
+					UpdatePostTx(gomock.Any(), gomock.Any()).
 					Times(0)
 
 			},
@@ -410,6 +510,64 @@ func TestUpdatePostAPI(t *testing.T) {
 
 			},
 		},
+		{
+
+			title: "Moderator",
+			body: gin.H{
+				"content": post.Content,
+			},
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "moderator", util.ModeratorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.PartialUpdatePostParams{
+					ID:      post.ID,
+					Content: sql.NullString{String: post.Content, Valid: true},
+				}
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdatePostTxResult{Post: post}, nil)
+
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchPost(t, recorder.Body, post)
+
+			},
+		},
+		{
+
+			title: "UnauthorizedUser",
+			body: gin.H{
+				"content": post.Content,
+			},
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized", util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+
+			},
+		},
 		{
 			title: "NoAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
@@ -417,7 +575,7 @@ func TestUpdatePostAPI(t *testing.T) {
 			buildStubs: func(store *mockdb.MockStore) {
 
 				store.EXPECT().
-					UpdatePost(gomock.Any(), gomock.Any()).
+					UpdatePostTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -464,134 +622,1331 @@ func TestUpdatePostAPI(t *testing.T) {
 
 }
 
-func TestCreatePostAPI(t *testing.T) {
+func TestPatchPostAPI(t *testing.T) {
 	user, _ := randomUser(t)
 	post := randomPost(user.UserName)
 
 	testCases := []struct {
-		name          string
+		title         string
 		body          gin.H
+		postID        int64
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
-		checkResponse func(recoder *httptest.ResponseRecorder)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
+
 		{
-			name: "OK",
+			title: "OK",
 			body: gin.H{
-				"image":    post.Image,
-				"title":    post.Title,
-				"subtitle": post.Subtitle,
-				"content":  post.Content,
+				"title": post.Title,
 			},
+			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				arg := db.CreatePostParams{
-					Owner:    post.Owner,
-					Image:    post.Image,
-					Title:    post.Title,
-					Subtitle: post.Subtitle,
-					Content:  post.Content,
+				arg := db.PartialUpdatePostParams{
+					ID:    post.ID,
+					Title: sql.NullString{String: post.Title, Valid: true},
 				}
-
 				store.EXPECT().
-					CreatePost(gomock.Any(), gomock.Eq(arg)).
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
 					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdatePostTxResult{Post: post}, nil)
+
 			},
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusCreated, recorder.Code)
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+
+				//check response
+				require.Equal(t, http.StatusOK, recorder.Code)
 				requireBodyMatchPost(t, recorder.Body, post)
 			},
 		},
 		{
-			name: "DuplicateTitle",
+			title: "AllFields",
 			body: gin.H{
 				"image":    post.Image,
 				"title":    post.Title,
 				"subtitle": post.Subtitle,
 				"content":  post.Content,
 			},
+			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.PartialUpdatePostParams{
+					ID:       post.ID,
+					Image:    sql.NullString{String: post.Image, Valid: true},
+					Title:    sql.NullString{String: post.Title, Valid: true},
+					Subtitle: sql.NullString{String: post.Subtitle, Valid: true},
+					Content:  sql.NullString{String: post.Content, Valid: true},
+				}
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
 				store.EXPECT().
-					CreatePost(gomock.Any(), gomock.Any()).
+					UpdatePostTx(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
-					Return(post, &pq.Error{Code: "23505"})
+					Return(db.UpdatePostTxResult{Post: post}, nil)
+
 			},
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+
+				//check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchPost(t, recorder.Body, post)
 			},
 		},
 		{
-			name: "NoAuthorization",
+			title:  "NoFields",
+			body:   gin.H{},
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+			},
+		},
+		{
+			title: "EmptyTitle",
 			body: gin.H{
-				"owner":    post.Owner,
-				"image":    post.Image,
-				"title":    post.Title,
-				"subtitle": post.Subtitle,
-				"content":  post.Content,
+				"title": "",
 			},
+			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-
 				store.EXPECT().
-					CreatePost(gomock.Any(), gomock.Any()).
+					UpdatePostTx(gomock.Any(), gomock.Any()).
 					Times(0)
+
 			},
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
 			},
 		},
 		{
-			name: "InternalError",
+			title: "DuplicateTitle",
 			body: gin.H{
-				"owner":    post.Owner,
-				"image":    post.Image,
-				"title":    post.Title,
-				"subtitle": post.Subtitle,
-				"content":  post.Content,
+				"title": post.Title,
 			},
+			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreatePost(gomock.Any(), gomock.Any()).
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
-					Return(db.Post{}, sql.ErrConnDone)
+					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdatePostTxResult{}, &pq.Error{Code: "23505"})
+
 			},
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyError(t, recorder.Body, errPostTitleTaken)
+
 			},
 		},
 		{
-			name: "InvalidBody",
+			title: "NotFound",
 			body: gin.H{
-				"owner": -1,
+				"title": post.Title,
 			},
+			postID: post.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreatePost(gomock.Any(), gomock.Any()).
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.Post{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
 					Times(0)
+
 			},
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+
 			},
 		},
-	}
-
-	for i := range testCases {
-		tc := testCases[i]
-
-		t.Run(tc.name, func(t *testing.T) {
+		{
+			title: "InternalError",
+			body: gin.H{
+				"title": post.Title,
+			},
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdatePostTxResult{}, sql.ErrConnDone)
+
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+			},
+		},
+		{
+			title: "UnauthorizedUser",
+			body: gin.H{
+				"title": post.Title,
+			},
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized", util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+
+			},
+		},
+		{
+			title: "NoAuthorization",
+			body: gin.H{
+				"title": post.Title,
+			},
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				//check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+			},
+		},
+	}
+
+	for i := range testCases {
+
+		tc := testCases[i]
+
+		t.Run(tc.title, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			//start test http server and send request
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/posts/%d", tc.postID)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+
+		})
+
+	}
+
+}
+
+func TestCreatePostAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	post := randomPost(user.UserName)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"image":    post.Image,
+				"title":    post.Title,
+				"subtitle": post.Subtitle,
+				"content":  post.Content,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreatePostWithTagsTxParams{
+					CreatePostParams: db.CreatePostParams{
+						Owner:    post.Owner,
+						Image:    post.Image,
+						Title:    post.Title,
+						Subtitle: post.Subtitle,
+						Content:  post.Content,
+					},
+				}
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CreatePostWithTagsTxResult{Post: post, Tags: []db.Tag{}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				requireBodyMatchPost(t, recorder.Body, post)
+			},
+		},
+		{
+			name: "OKWithCategory",
+			body: gin.H{
+				"image":       post.Image,
+				"title":       post.Title,
+				"subtitle":    post.Subtitle,
+				"content":     post.Content,
+				"category_id": 7,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				categoryID := int64(7)
+				arg := db.CreatePostWithTagsTxParams{
+					CreatePostParams: db.CreatePostParams{
+						Owner:      post.Owner,
+						Image:      post.Image,
+						Title:      post.Title,
+						Subtitle:   post.Subtitle,
+						Content:    post.Content,
+						CategoryID: &categoryID,
+					},
+				}
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CreatePostWithTagsTxResult{Post: post, Tags: []db.Tag{}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "OKWithTags",
+			body: gin.H{
+				"image":    post.Image,
+				"title":    post.Title,
+				"subtitle": post.Subtitle,
+				"content":  post.Content,
+				"tags":     []string{"xmr", "fees", "xmr"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreatePostWithTagsTxParams{
+					CreatePostParams: db.CreatePostParams{
+						Owner:    post.Owner,
+						Image:    post.Image,
+						Title:    post.Title,
+						Subtitle: post.Subtitle,
+						Content:  post.Content,
+					},
+					Tags: []string{"xmr", "fees"},
+				}
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CreatePostWithTagsTxResult{
+						Post: post,
+						Tags: []db.Tag{{ID: 1, Name: "xmr"}, {ID: 2, Name: "fees"}},
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var gotPost postResponse
+				err := json.NewDecoder(recorder.Body).Decode(&gotPost)
+				require.NoError(t, err)
+				require.Equal(t, []string{"xmr", "fees"}, gotPost.Tags)
+			},
+		},
+		{
+			name: "InvalidTag",
+			body: gin.H{
+				"image":    post.Image,
+				"title":    post.Title,
+				"subtitle": post.Subtitle,
+				"content":  post.Content,
+				"tags":     []string{"Not A Tag"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownCategory",
+			body: gin.H{
+				"image":       post.Image,
+				"title":       post.Title,
+				"subtitle":    post.Subtitle,
+				"content":     post.Content,
+				"category_id": 7,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreatePostWithTagsTxResult{}, &pq.Error{Code: "23503"})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "DuplicateTitle",
+			body: gin.H{
+				"image":    post.Image,
+				"title":    post.Title,
+				"subtitle": post.Subtitle,
+				"content":  post.Content,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreatePostWithTagsTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "EmailNotVerified",
+			body: gin.H{
+				"image":    post.Image,
+				"title":    post.Title,
+				"subtitle": post.Subtitle,
+				"content":  post.Content,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				unverified := user
+				unverified.IsEmailVerified = false
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(unverified, nil)
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"owner":    post.Owner,
+				"image":    post.Image,
+				"title":    post.Title,
+				"subtitle": post.Subtitle,
+				"content":  post.Content,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"owner":    post.Owner,
+				"image":    post.Image,
+				"title":    post.Title,
+				"subtitle": post.Subtitle,
+				"content":  post.Content,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreatePostWithTagsTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidBody",
+			body: gin.H{
+				"owner": -1,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreatePostWithTagsTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/api/posts"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListPostsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 5
+	posts := make([]db.Post, n)
+	for i := 0; i < n; i++ {
+		posts[i] = randomPost(user.UserName)
+	}
+	count := int64(len(posts))
+
+	category := randomCategory()
+
+	type Query struct {
+		pageID   int
+		pageSize int
+		category string
+		tags     []string
+		match    string
+		after    string
+		limit    int
+		sort     string
+	}
+
+	cursor := postCursor{CreatedAt: time.Now().UTC().Truncate(time.Microsecond), ID: 10}
+
+	testCases := []struct {
+		name          string
+		query         Query
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListPostsParams{
+					SortKey: "id",
+					Limit:   int32(n),
+					Offset:  0,
+				}
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Eq(db.CountPostsParams{})).
+					Times(1).
+					Return(count, nil)
+
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(posts, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotEmpty(t, posts)
+			},
+		},
+		{
+			name: "OKWithCategory",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+				category: category.Slug,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				categoryID := sql.NullInt64{Int64: category.ID, Valid: true}
+				arg := db.ListPostsParams{
+					CategoryID: categoryID,
+					SortKey:    "id",
+					Limit:      int32(n),
+					Offset:     0,
+				}
+				store.EXPECT().
+					GetCategoryBySlug(gomock.Any(), gomock.Eq(category.Slug)).
+					Times(1).
+					Return(category, nil)
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Eq(db.CountPostsParams{CategoryID: categoryID})).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(posts[:1], nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var resp struct {
+					TotalRecords int64     `json:"total_records"`
+					LastPage     int64     `json:"last_page"`
+					Posts        []db.Post `json:"posts"`
+				}
+				err := json.NewDecoder(recorder.Body).Decode(&resp)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), resp.TotalRecords)
+				require.Equal(t, int64(1), resp.LastPage)
+				require.Len(t, resp.Posts, 1)
+			},
+		},
+		{
+			name: "OKWithAllTags",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+				tags:     []string{"xmr", "fees", "xmr"},
+				match:    "all",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				tags := []string{"xmr", "fees"}
+				arg := db.ListPostsParams{
+					Tags:     tags,
+					MatchAll: true,
+					SortKey:  "id",
+					Limit:    int32(n),
+					Offset:   0,
+				}
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Eq(db.CountPostsParams{Tags: tags, MatchAll: true})).
+					Times(1).
+					Return(int64(2), nil)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(posts[:2], nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OKWithAnyTag",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+				tags:     []string{"xmr", "fees"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListPostsParams{
+					Tags:    []string{"xmr", "fees"},
+					SortKey: "id",
+					Limit:   int32(n),
+					Offset:  0,
+				}
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(count, nil)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(posts, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidMatch",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+				tags:     []string{"xmr"},
+				match:    "some",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "OKFirstCursorPage",
+			query: Query{
+				limit: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListPostsAfterParams{
+					Limit: int32(n + 1),
+				}
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ListPostsAfter(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(append(posts[:n:n], randomPost(user.UserName)), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var resp struct {
+					NextCursor string    `json:"next_cursor"`
+					Posts      []db.Post `json:"posts"`
+				}
+				err := json.NewDecoder(recorder.Body).Decode(&resp)
+				require.NoError(t, err)
+				require.Len(t, resp.Posts, n)
+
+				next, err := decodePostCursor(resp.NextCursor)
+				require.NoError(t, err)
+				require.Equal(t, posts[n-1].ID, next.ID)
+			},
+		},
+		{
+			name: "OKLastCursorPage",
+			query: Query{
+				after: cursor.encode(),
+				limit: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListPostsAfterParams{
+					AfterCreatedAt: sql.NullTime{Time: cursor.CreatedAt, Valid: true},
+					AfterID:        cursor.ID,
+					Limit:          int32(n + 1),
+				}
+				store.EXPECT().
+					ListPostsAfter(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(posts, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var resp struct {
+					NextCursor string    `json:"next_cursor"`
+					Posts      []db.Post `json:"posts"`
+				}
+				err := json.NewDecoder(recorder.Body).Decode(&resp)
+				require.NoError(t, err)
+				require.Len(t, resp.Posts, n)
+				require.Empty(t, resp.NextCursor)
+			},
+		},
+		{
+			name: "InvalidCursor",
+			query: Query{
+				after: "not-a-cursor",
+				limit: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPostsAfter(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "CursorWithoutLimit",
+			query: Query{
+				after: cursor.encode(),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPostsAfter(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "SortWithCursor",
+			query: Query{
+				limit: n,
+				sort:  "title",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPostsAfter(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MixedPagingModes",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+				limit:    n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ListPostsAfter(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingPaging",
+			query: Query{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "CategoryNotFound",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+				category: category.Slug,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetCategoryBySlug(gomock.Any(), gomock.Eq(category.Slug)).
+					Times(1).
+					Return(db.Category{}, sql.ErrNoRows)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCategory",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+				category: "Not A Slug",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetCategoryBySlug(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Eq(db.CountPostsParams{})).
+					Times(1).
+					Return(count, nil)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Post{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPageID",
+			query: Query{
+				pageID:   -1,
+				pageSize: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/api/posts"
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			// Add query parameters to request URL
+			q := request.URL.Query()
+			if tc.query.pageID != 0 {
+				q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			}
+			if tc.query.pageSize != 0 {
+				q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			}
+			if tc.query.after != "" {
+				q.Add("after", tc.query.after)
+			}
+			if tc.query.limit != 0 {
+				q.Add("limit", fmt.Sprintf("%d", tc.query.limit))
+			}
+			if tc.query.sort != "" {
+				q.Add("sort", tc.query.sort)
+			}
+			if tc.query.category != "" {
+				q.Add("category", tc.query.category)
+			}
+			for _, tag := range tc.query.tags {
+				q.Add("tag", tag)
+			}
+			if tc.query.match != "" {
+				q.Add("match", tc.query.match)
+			}
+			request.URL.RawQuery = q.Encode()
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListPostsSortAndFilterAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 5
+	posts := make([]db.Post, n)
+	for i := 0; i < n; i++ {
+		posts[i] = randomPost(user.UserName)
+	}
+
+	after := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		query      string
+		arg        *db.ListPostsParams
+		statusCode int
+	}{
+		{
+			name:       "Default",
+			query:      "",
+			arg:        &db.ListPostsParams{SortKey: "id"},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Newest",
+			query:      "&sort=newest",
+			arg:        &db.ListPostsParams{SortKey: "created_at", SortDesc: true},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Oldest",
+			query:      "&sort=oldest",
+			arg:        &db.ListPostsParams{SortKey: "created_at"},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Title",
+			query:      "&sort=title",
+			arg:        &db.ListPostsParams{SortKey: "title"},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "TitleDescending",
+			query:      "&sort=title&order=desc",
+			arg:        &db.ListPostsParams{SortKey: "title", SortDesc: true},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "MostCommented",
+			query:      "&sort=most-commented",
+			arg:        &db.ListPostsParams{SortKey: "comments", SortDesc: true},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "MostCommentedAscending",
+			query:      "&sort=most-commented&order=asc",
+			arg:        &db.ListPostsParams{SortKey: "comments"},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "DescendingID",
+			query:      "&order=desc",
+			arg:        &db.ListPostsParams{SortKey: "id", SortDesc: true},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:  "OwnerAndDates",
+			query: "&owner=" + user.UserName + "&created_after=2022-01-01T00:00:00Z&created_before=2022-06-01T00:00:00Z",
+			arg: &db.ListPostsParams{
+				Owner:         sql.NullString{String: user.UserName, Valid: true},
+				CreatedAfter:  sql.NullTime{Time: after, Valid: true},
+				CreatedBefore: sql.NullTime{Time: before, Valid: true},
+				SortKey:       "id",
+			},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "InvalidSort",
+			query:      "&sort=top-voted",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "InvalidOrder",
+			query:      "&order=sideways",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "InvalidOwner",
+			query:      "&owner=not%20a%20user",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "InvalidDate",
+			query:      "&created_after=yesterday",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "BeforeNotAfterStart",
+			query:      "&created_after=2022-06-01T00:00:00Z&created_before=2022-01-01T00:00:00Z",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+
+			if tc.arg != nil {
+				arg := *tc.arg
+				arg.Limit = int32(n)
+				arg.Offset = 0
+
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Eq(db.CountPostsParams{
+						Owner:         arg.Owner,
+						CreatedAfter:  arg.CreatedAfter,
+						CreatedBefore: arg.CreatedBefore,
+					})).
+					Times(1).
+					Return(int64(n), nil)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(posts, nil)
+			} else {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			}
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/posts?page_id=1&page_size=%d%s", n, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.statusCode, recorder.Code)
+		})
+	}
+}
+
+func TestSearchPostsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 5
+	rows := make([]db.SearchPostsRow, n)
+	for i := 0; i < n; i++ {
+		post := randomPost(user.UserName)
+		rows[i] = db.SearchPostsRow{
+			ID:       post.ID,
+			Owner:    post.Owner,
+			Title:    post.Title,
+			Content:  post.Content,
+			Rank:     float32(n-i) / 10,
+			Headline: "<mark>" + post.Title + "</mark>",
+		}
+	}
+
+	type Query struct {
+		q        string
+		pageID   int
+		pageSize int
+	}
+
+	testCases := []struct {
+		name          string
+		query         Query
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: Query{
+				q:        "monero fees",
+				pageID:   2,
+				pageSize: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SearchPostsParams{
+					Query:  "monero fees",
+					Limit:  int32(n),
+					Offset: int32(n),
+				}
+				store.EXPECT().
+					CountSearchPosts(gomock.Any(), gomock.Eq("monero fees")).
+					Times(1).
+					Return(int64(2*n), nil)
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(rows, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var resp struct {
+					TotalRecords int64               `json:"total_records"`
+					LastPage     int64               `json:"last_page"`
+					Posts        []db.SearchPostsRow `json:"posts"`
+				}
+				err := json.NewDecoder(recorder.Body).Decode(&resp)
+				require.NoError(t, err)
+				require.Equal(t, int64(2*n), resp.TotalRecords)
+				require.Equal(t, int64(2), resp.LastPage)
+				require.Equal(t, rows, resp.Posts)
+			},
+		},
+		{
+			name: "MissingQuery",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPageSize",
+			query: Query{
+				q:        "monero",
+				pageID:   1,
+				pageSize: 100,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			query: Query{
+				q:        "monero",
+				pageID:   1,
+				pageSize: n,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CountSearchPosts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
@@ -601,14 +1956,190 @@ func TestCreatePostAPI(t *testing.T) {
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
-			// Marshal body data to JSON
-			json := jsoniter.ConfigCompatibleWithStandardLibrary
-
-			data, err := json.Marshal(tc.body)
+			url := "/api/posts/search"
+			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
 
-			url := "/api/posts"
-			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			// Add query parameters to request URL
+			q := request.URL.Query()
+			if tc.query.q != "" {
+				q.Add("q", tc.query.q)
+			}
+			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			request.URL.RawQuery = q.Encode()
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRestorePostAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	post := randomPost(user.UserName)
+	deletedAt := time.Now().Add(-time.Hour)
+	deleted := post
+	deleted.DeletedAt = &deletedAt
+
+	testCases := []struct {
+		name          string
+		postID        int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetDeletedPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(deleted, nil)
+				store.EXPECT().
+					RestorePost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchPost(t, recorder.Body, post)
+			},
+		},
+		{
+			name:   "Moderator",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "moderator", util.ModeratorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetDeletedPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(deleted, nil)
+				store.EXPECT().
+					RestorePost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "TitleTaken",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetDeletedPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(deleted, nil)
+				store.EXPECT().
+					RestorePost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.Post{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyError(t, recorder.Body, errPostTitleTaken)
+			},
+		},
+		{
+			name:   "UnauthorizedUser",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized", util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetDeletedPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(deleted, nil)
+				store.EXPECT().
+					RestorePost(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "NotInTrash",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetDeletedPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.Post{}, sql.ErrNoRows)
+				store.EXPECT().
+					RestorePost(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "NoAuthorization",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetDeletedPost(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			postID: post.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetDeletedPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(deleted, nil)
+				store.EXPECT().
+					RestorePost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.Post{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/posts/%d/restore", tc.postID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
 			require.NoError(t, err)
 
 			tc.setupAuth(t, request, server.tokenMaker)
@@ -618,86 +2149,96 @@ func TestCreatePostAPI(t *testing.T) {
 	}
 }
 
-func TestListPostsAPI(t *testing.T) {
+func TestListTrashAPI(t *testing.T) {
 	user, _ := randomUser(t)
 
 	n := 5
 	posts := make([]db.Post, n)
 	for i := 0; i < n; i++ {
+		deletedAt := time.Now().Add(-time.Duration(i) * time.Hour)
 		posts[i] = randomPost(user.UserName)
-	}
-	count := int64(len(posts))
-
-	type Query struct {
-		pageID   int
-		pageSize int
+		posts[i].DeletedAt = &deletedAt
 	}
 
 	testCases := []struct {
 		name          string
-		query         Query
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
-		checkResponse func(recoder *httptest.ResponseRecorder)
+		checkResponse func(recorder *httptest.ResponseRecorder)
 	}{
 		{
-			name: "OK",
-			query: Query{
-				pageID:   1,
-				pageSize: n,
+			name:  "OK",
+			query: fmt.Sprintf("page_id=2&page_size=%d", n),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				arg := db.ListPostsParams{
+				arg := db.ListDeletedPostsByOwnerParams{
+					Owner:  user.UserName,
 					Limit:  int32(n),
-					Offset: 0,
+					Offset: int32(n),
 				}
 				store.EXPECT().
-					CountPosts(gomock.Any()).
-					Times(1).
-					Return(count, nil)
-
-				store.EXPECT().
-					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					ListDeletedPostsByOwner(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
 					Return(posts, nil)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
-				require.NotEmpty(t, posts)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var gotPosts []db.Post
+				err := json.NewDecoder(recorder.Body).Decode(&gotPosts)
+				require.NoError(t, err)
+				require.Len(t, gotPosts, n)
+				require.NotNil(t, gotPosts[0].DeletedAt)
 			},
 		},
 		{
-			name: "InternalError",
-			query: Query{
-				pageID:   1,
-				pageSize: n,
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=100",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CountPosts(gomock.Any()).
-					Times(1).
-					Return(count, nil)
-				store.EXPECT().
-					ListPosts(gomock.Any(), gomock.Any()).
-					Times(1).
-					Return([]db.Post{}, sql.ErrConnDone)
+					ListDeletedPostsByOwner(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
 		{
-			name: "InvalidPageID",
-			query: Query{
-				pageID:   -1,
-				pageSize: n,
+			name:  "NoAuthorization",
+			query: fmt.Sprintf("page_id=1&page_size=%d", n),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListPosts(gomock.Any(), gomock.Any()).
+					ListDeletedPostsByOwner(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: fmt.Sprintf("page_id=1&page_size=%d", n),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDeletedPostsByOwner(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Post{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
 	}
@@ -715,16 +2256,11 @@ func TestListPostsAPI(t *testing.T) {
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
-			url := "/api/posts"
+			url := "/api/users/me/trash?" + tc.query
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
 
-			// Add query parameters to request URL
-			q := request.URL.Query()
-			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
-			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
-			request.URL.RawQuery = q.Encode()
-
+			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(recorder)
 		})
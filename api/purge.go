@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// How often soft-deleted posts past their retention are purged
+const purgeInterval = time.Hour
+
+// purgeDeletedPosts permanently removes the posts that have been in the trash
+// for longer than the configured retention, every interval until ctx is done.
+// A retention of zero keeps deleted posts forever.
+func (server *Server) purgeDeletedPosts(ctx context.Context, interval time.Duration) {
+	retention := server.config.DeletedPostRetention
+	if retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := server.store.PurgeDeletedPosts(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Println("cannot purge deleted posts:", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("purged %d deleted posts", purged)
+			}
+		}
+	}
+}
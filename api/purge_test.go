@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeDeletedPosts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	server.config.DeletedPostRetention = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	purged := make(chan time.Time, 1)
+	store.EXPECT().
+		PurgeDeletedPosts(gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(_ context.Context, deletedBefore time.Time) (int64, error) {
+			select {
+			case purged <- deletedBefore:
+			default:
+			}
+			return 1, nil
+		})
+
+	done := make(chan struct{})
+	go func() {
+		server.purgeDeletedPosts(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case deletedBefore := <-purged:
+		// Only posts deleted more than the retention ago are purged
+		require.WithinDuration(t, time.Now().Add(-time.Hour), deletedBefore, time.Second)
+	case <-time.After(time.Second):
+		require.FailNow(t, "deleted posts were not purged")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestPurgeDeletedPostsDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		PurgeDeletedPosts(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	server.config.DeletedPostRetention = 0
+
+	// Returns right away instead of ticking when the retention is zero
+	server.purgeDeletedPosts(context.Background(), time.Millisecond)
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+)
+
+// How often buckets that have refilled completely are dropped
+const rateLimitPruneInterval = 5 * time.Minute
+
+// Route groups with their own rate limit
+const (
+	rateLimitPublic  = "public"
+	rateLimitAccount = "account"
+	rateLimitUser    = "user"
+	rateLimitPosting = "posting"
+)
+
+const (
+	rateLimitBackendMemory   = "memory"
+	rateLimitBackendPostgres = "postgres"
+)
+
+var errRateLimited = errors.New("rate limit exceeded, try again later")
+
+// rateLimitPolicy is a token bucket holding up to burst tokens that refills
+// completely over period, every request takes one token
+type rateLimitPolicy struct {
+	burst  int
+	period time.Duration
+}
+
+// parseRateLimitPolicy reads a policy written as burst/period, e.g. 10/1m.
+// An empty spec means the route group is not limited.
+func parseRateLimitPolicy(spec string) (*rateLimitPolicy, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(spec, "/")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid rate limit %q, expected burst/period", spec)
+	}
+
+	burst, err := strconv.Atoi(fields[0])
+	if err != nil || burst < 1 {
+		return nil, fmt.Errorf("invalid rate limit burst %q", fields[0])
+	}
+
+	period, err := time.ParseDuration(fields[1])
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("invalid rate limit period %q", fields[1])
+	}
+
+	return &rateLimitPolicy{burst: burst, period: period}, nil
+}
+
+// Tokens added to the bucket per second
+func (policy rateLimitPolicy) rate() float64 {
+	return float64(policy.burst) / policy.period.Seconds()
+}
+
+// State of a bucket right after a request tried to take a token
+type rateLimitResult struct {
+	tokens  float64
+	allowed bool
+}
+
+// rateLimitBackend keeps the token buckets, either in memory for a single
+// instance or in Postgres so that several instances share the limits
+type rateLimitBackend interface {
+	take(ctx context.Context, key string, policy rateLimitPolicy) (rateLimitResult, error)
+	prune(ctx context.Context, updatedBefore time.Time) error
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+type memoryRateLimitBackend struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newMemoryRateLimitBackend() *memoryRateLimitBackend {
+	return &memoryRateLimitBackend{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (backend *memoryRateLimitBackend) take(ctx context.Context, key string, policy rateLimitPolicy) (rateLimitResult, error) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := backend.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(policy.burst)}
+		backend.buckets[key] = bucket
+	} else {
+		bucket.tokens = math.Min(float64(policy.burst), bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*policy.rate())
+	}
+	bucket.updatedAt = now
+
+	if bucket.tokens < 1 {
+		return rateLimitResult{tokens: bucket.tokens, allowed: false}, nil
+	}
+
+	bucket.tokens--
+	return rateLimitResult{tokens: bucket.tokens, allowed: true}, nil
+}
+
+func (backend *memoryRateLimitBackend) prune(ctx context.Context, updatedBefore time.Time) error {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+
+	for key, bucket := range backend.buckets {
+		if bucket.updatedAt.Before(updatedBefore) {
+			delete(backend.buckets, key)
+		}
+	}
+
+	return nil
+}
+
+// postgresRateLimitBackend refills and takes from the bucket in a single
+// statement so concurrent requests from any instance cannot overspend it
+type postgresRateLimitBackend struct {
+	store db.Store
+}
+
+func (backend *postgresRateLimitBackend) take(ctx context.Context, key string, policy rateLimitPolicy) (rateLimitResult, error) {
+	row, err := backend.store.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(policy.burst),
+		Rate:  policy.rate(),
+	})
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+
+	return rateLimitResult{tokens: row.Tokens, allowed: row.Allowed}, nil
+}
+
+func (backend *postgresRateLimitBackend) prune(ctx context.Context, updatedBefore time.Time) error {
+	_, err := backend.store.DeleteStaleRateLimitBuckets(ctx, updatedBefore)
+	return err
+}
+
+// rateLimiter applies the policy of each route group from the config
+type rateLimiter struct {
+	backend  rateLimitBackend
+	policies map[string]*rateLimitPolicy
+}
+
+func newRateLimiter(config util.Config, store db.Store) (*rateLimiter, error) {
+	limiter := &rateLimiter{
+		policies: make(map[string]*rateLimitPolicy),
+	}
+
+	switch config.RateLimitBackend {
+	case "", rateLimitBackendMemory:
+		limiter.backend = newMemoryRateLimitBackend()
+	case rateLimitBackendPostgres:
+		limiter.backend = &postgresRateLimitBackend{store: store}
+	default:
+		return nil, fmt.Errorf("unsupported rate limit backend %q", config.RateLimitBackend)
+	}
+
+	specs := map[string]string{
+		rateLimitPublic:  config.RateLimitPublic,
+		rateLimitAccount: config.RateLimitAccount,
+		rateLimitUser:    config.RateLimitUser,
+		rateLimitPosting: config.RateLimitPosting,
+	}
+	for group, spec := range specs {
+		policy, err := parseRateLimitPolicy(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group, err)
+		}
+		limiter.policies[group] = policy
+	}
+
+	return limiter, nil
+}
+
+// limit returns the middleware enforcing the policy of the route group.
+// Requests are counted per authenticated user name, or per client IP before authentication.
+func (limiter *rateLimiter) limit(group string) gin.HandlerFunc {
+	policy := limiter.policies[group]
+	if policy == nil {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		result, err := limiter.backend.take(ctx, group+":"+rateLimitKey(ctx), *policy)
+		if err != nil {
+			// An unavailable backend must not take the whole API down with it
+			log.Println("cannot take rate limit token:", err)
+			ctx.Next()
+			return
+		}
+
+		rate := policy.rate()
+		ctx.Header("RateLimit-Limit", strconv.Itoa(policy.burst))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(int(math.Floor(result.tokens))))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil((float64(policy.burst)-result.tokens)/rate))))
+		ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.burst, int(math.Ceil(policy.period.Seconds()))))
+
+		if !result.allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil((1-result.tokens)/rate))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(errRateLimited))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func rateLimitKey(ctx *gin.Context) string {
+	if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+		return "user:" + payload.(*token.Payload).UserName
+	}
+
+	return "ip:" + ctx.ClientIP()
+}
+
+// expire drops the buckets that have had time to refill completely every interval until ctx is done
+func (limiter *rateLimiter) expire(ctx context.Context, interval time.Duration) {
+	var longest time.Duration
+	for _, policy := range limiter.policies {
+		if policy != nil && policy.period > longest {
+			longest = policy.period
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := limiter.backend.prune(ctx, time.Now().Add(-longest)); err != nil {
+				log.Println("cannot prune rate limit buckets:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/mail"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimitPolicy(t *testing.T) {
+	policy, err := parseRateLimitPolicy("10/1m")
+	require.NoError(t, err)
+	require.Equal(t, 10, policy.burst)
+	require.Equal(t, time.Minute, policy.period)
+	require.InDelta(t, 10.0/60, policy.rate(), 1e-9)
+
+	policy, err = parseRateLimitPolicy("")
+	require.NoError(t, err)
+	require.Nil(t, policy)
+
+	for _, spec := range []string{"10", "10/", "0/1m", "x/1m", "10/0s", "10/1m/1m"} {
+		_, err := parseRateLimitPolicy(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	_, err := newRateLimiter(util.Config{RateLimitBackend: "redis"}, nil)
+	require.Error(t, err)
+
+	_, err = newRateLimiter(util.Config{RateLimitUser: "many"}, nil)
+	require.Error(t, err)
+
+	limiter, err := newRateLimiter(util.Config{RateLimitBackend: rateLimitBackendPostgres}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &postgresRateLimitBackend{}, limiter.backend)
+}
+
+func TestMemoryRateLimitBackend(t *testing.T) {
+	backend := newMemoryRateLimitBackend()
+	policy := rateLimitPolicy{burst: 2, period: time.Hour}
+
+	result, err := backend.take(context.Background(), "a", policy)
+	require.NoError(t, err)
+	require.True(t, result.allowed)
+	require.InDelta(t, 1, result.tokens, 0.01)
+
+	result, err = backend.take(context.Background(), "a", policy)
+	require.NoError(t, err)
+	require.True(t, result.allowed)
+
+	result, err = backend.take(context.Background(), "a", policy)
+	require.NoError(t, err)
+	require.False(t, result.allowed)
+
+	// Every key has its own bucket
+	result, err = backend.take(context.Background(), "b", policy)
+	require.NoError(t, err)
+	require.True(t, result.allowed)
+
+	err = backend.prune(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, backend.buckets)
+}
+
+func TestMemoryRateLimitBackendRefill(t *testing.T) {
+	backend := newMemoryRateLimitBackend()
+	policy := rateLimitPolicy{burst: 1, period: 50 * time.Millisecond}
+
+	result, err := backend.take(context.Background(), "a", policy)
+	require.NoError(t, err)
+	require.True(t, result.allowed)
+
+	result, err = backend.take(context.Background(), "a", policy)
+	require.NoError(t, err)
+	require.False(t, result.allowed)
+
+	time.Sleep(60 * time.Millisecond)
+
+	result, err = backend.take(context.Background(), "a", policy)
+	require.NoError(t, err)
+	require.True(t, result.allowed)
+}
+
+func TestPostgresRateLimitBackend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	arg := db.TakeRateLimitTokenParams{
+		Key:   "a",
+		Burst: 60,
+		Rate:  1,
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(db.TakeRateLimitTokenRow{Tokens: 59, Allowed: true}, nil)
+	store.EXPECT().
+		DeleteStaleRateLimitBuckets(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(1), nil)
+
+	backend := &postgresRateLimitBackend{store: store}
+
+	result, err := backend.take(context.Background(), "a", rateLimitPolicy{burst: 60, period: time.Minute})
+	require.NoError(t, err)
+	require.Equal(t, rateLimitResult{tokens: 59, allowed: true}, result)
+
+	err = backend.prune(context.Background(), time.Now())
+	require.NoError(t, err)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	server := newTestServer(t, nil)
+	limiter, err := newRateLimiter(util.Config{RateLimitUser: "2/1h"}, nil)
+	require.NoError(t, err)
+
+	limitPath := "/limit"
+	server.router.GET(
+		limitPath,
+		limiter.limit(rateLimitUser),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		},
+	)
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, limitPath, nil)
+		require.NoError(t, err)
+		request.RemoteAddr = remoteAddr
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := get("10.0.0.1:1234")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "1800", recorder.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "2;w=3600", recorder.Header().Get("RateLimit-Policy"))
+
+	recorder = get("10.0.0.1:1234")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = get("10.0.0.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "1800", recorder.Header().Get("Retry-After"))
+
+	// Another client IP is not affected
+	recorder = get("10.0.0.2:1234")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimitMiddlewareAuthenticated(t *testing.T) {
+	server := newTestServer(t, nil)
+	limiter, err := newRateLimiter(util.Config{RateLimitUser: "1/1h"}, nil)
+	require.NoError(t, err)
+
+	authPath := "/api/auth"
+	server.router.GET(
+		authPath,
+		authMiddleware(server.tokenMaker, server.revocations, server.generations, server.store),
+		limiter.limit(rateLimitUser),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		},
+	)
+
+	get := func(userName string) int {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, authPath, nil)
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, userName, util.UserRole, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// Authenticated requests are counted per user name, not per IP
+	require.Equal(t, http.StatusOK, get("user1"))
+	require.Equal(t, http.StatusTooManyRequests, get("user1"))
+	require.Equal(t, http.StatusOK, get("user2"))
+}
+
+func TestRateLimitMiddlewareBackendError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.TakeRateLimitTokenRow{}, sql.ErrConnDone)
+
+	server := newTestServer(t, store)
+	limiter, err := newRateLimiter(util.Config{RateLimitBackend: rateLimitBackendPostgres, RateLimitPublic: "1/1h"}, store)
+	require.NoError(t, err)
+
+	limitPath := "/limit"
+	server.router.GET(
+		limitPath,
+		limiter.limit(rateLimitPublic),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		},
+	)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, limitPath, nil)
+	require.NoError(t, err)
+
+	// The request goes through when the limits cannot be checked
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimitedRoutes(t *testing.T) {
+	config := util.Config{
+		TokenSymmetricKey:     util.RandomString(32),
+		AccessTokenDuration:   time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		RateLimitAccount:      "1/1h",
+		EmailSender:           mail.MemorySenderKind,
+		TwoFactorSymmetricKey: util.RandomString(32),
+	}
+
+	server, err := NewServer(config, nil)
+	require.NoError(t, err)
+
+	// The account routes share one bucket, the first request is rejected for its invalid body
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/api/users/login", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodPost, "/api/users", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
+
+func TestRateLimitedAuthRoutes(t *testing.T) {
+	config := util.Config{
+		TokenSymmetricKey:     util.RandomString(32),
+		AccessTokenDuration:   time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		RateLimitPublic:       "1/1h",
+		EmailSender:           mail.MemorySenderKind,
+		TwoFactorSymmetricKey: util.RandomString(32),
+	}
+
+	server, err := NewServer(config, nil)
+	require.NoError(t, err)
+
+	// Failed authentications count against the limit of the client IP
+	for _, code := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/api/users/me", nil)
+		require.NoError(t, err)
+
+		request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" invalid")
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, code, recorder.Code)
+	}
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type postRevisionRequestID struct {
+	ID       int64 `uri:"id" binding:"required,min=1"`
+	Revision int32 `uri:"rev" binding:"required,min=1"`
+}
+
+type listPostRevisionsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=15"`
+}
+
+//Leaving out to compares against the current version of the post
+type diffPostRequest struct {
+	From int32 `form:"from" binding:"required,min=1"`
+	To   int32 `form:"to" binding:"omitempty,min=1"`
+}
+
+type diffPostResponse struct {
+	From int32  `json:"from"`
+	To   int32  `json:"to"`
+	Diff string `json:"diff"`
+}
+
+func (server *Server) listPostRevisions(ctx *gin.Context) {
+
+	var id getPostRequest
+	var req listPostRevisionsRequest
+
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if _, ok := server.getVisiblePost(ctx, id.ID); !ok {
+		return
+	}
+
+	args := db.ListPostRevisionsParams{
+
+		PostID: id.ID,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	revisions, err := server.store.ListPostRevisions(ctx, args)
+
+	if err != nil {
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revisions)
+
+}
+
+func (server *Server) getPostRevision(ctx *gin.Context) {
+
+	var req postRevisionRequestID
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if _, ok := server.getVisiblePost(ctx, req.ID); !ok {
+		return
+	}
+
+	revision, ok := server.getRevision(ctx, req.ID, req.Revision)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revision)
+
+}
+
+func (server *Server) diffPost(ctx *gin.Context) {
+
+	var id getPostRequest
+	var req diffPostRequest
+
+	if err := ctx.ShouldBindUri(&id); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	post, ok := server.getVisiblePost(ctx, id.ID)
+	if !ok {
+		return
+	}
+
+	from, ok := server.getRevision(ctx, id.ID, req.From)
+	if !ok {
+		return
+	}
+
+	toName := "current"
+	toContent := post.Content
+
+	if req.To != 0 {
+
+		to, ok := server.getRevision(ctx, id.ID, req.To)
+		if !ok {
+			return
+		}
+
+		toName = fmt.Sprintf("revision %d", to.Revision)
+		toContent = to.Content
+
+	}
+
+	resp := diffPostResponse{
+
+		From: req.From,
+		To:   req.To,
+		Diff: util.UnifiedDiff(fmt.Sprintf("revision %d", from.Revision), toName, from.Content, toContent),
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+
+}
+
+//revertPost puts a revision back as the current version, the replaced version is kept as a new revision
+func (server *Server) revertPost(ctx *gin.Context) {
+
+	var req postRevisionRequestID
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+
+	}
+
+	if _, ok := server.getOwnedPost(ctx, req.ID); !ok {
+		return
+	}
+
+	revision, ok := server.getRevision(ctx, req.ID, req.Revision)
+	if !ok {
+		return
+	}
+
+	args := db.PartialUpdatePostParams{
+
+		ID:       req.ID,
+		Image:    sql.NullString{String: revision.Image, Valid: true},
+		Title:    sql.NullString{String: revision.Title, Valid: true},
+		Subtitle: sql.NullString{String: revision.Subtitle, Valid: true},
+		Content:  sql.NullString{String: revision.Content, Valid: true},
+	}
+
+	result, err := server.store.UpdatePostTx(ctx, args)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusConflict, errorResponse(errPostTitleTaken))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result.Post)
+
+}
+
+// getVisiblePost fetches a post that hasn't been deleted,
+// the error response is already written when false is returned
+func (server *Server) getVisiblePost(ctx *gin.Context, id int64) (db.Post, bool) {
+
+	post, err := server.store.GetPost(ctx, id)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return post, false
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return post, false
+	}
+
+	return post, true
+
+}
+
+// getRevision fetches a revision of the post,
+// the error response is already written when false is returned
+func (server *Server) getRevision(ctx *gin.Context, postID int64, revision int32) (db.PostRevision, bool) {
+
+	arg := db.GetPostRevisionParams{
+
+		PostID:   postID,
+		Revision: revision,
+	}
+
+	rev, err := server.store.GetPostRevision(ctx, arg)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return rev, false
+
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return rev, false
+	}
+
+	return rev, true
+
+}
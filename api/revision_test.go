@@ -0,0 +1,579 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/golang/mock/gomock"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPostRevisionsAPI(t *testing.T) {
+	post := randomPost(util.RandomOwner())
+
+	n := 5
+	revisions := make([]db.PostRevision, n)
+	for i := 0; i < n; i++ {
+		revisions[i] = randomRevision(post, int32(n-i))
+	}
+
+	testCases := []struct {
+		name          string
+		postID        int64
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			postID: post.ID,
+			query:  fmt.Sprintf("page_id=1&page_size=%d", n),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListPostRevisionsParams{
+					PostID: post.ID,
+					Limit:  int32(n),
+					Offset: 0,
+				}
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					ListPostRevisions(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(revisions, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var gotRevisions []db.PostRevision
+				err := json.NewDecoder(recorder.Body).Decode(&gotRevisions)
+				require.NoError(t, err)
+				require.Len(t, gotRevisions, n)
+				require.Equal(t, revisions[0].Revision, gotRevisions[0].Revision)
+			},
+		},
+		{
+			name:   "PostNotFound",
+			postID: post.ID,
+			query:  fmt.Sprintf("page_id=1&page_size=%d", n),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.Post{}, sql.ErrNoRows)
+				store.EXPECT().
+					ListPostRevisions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidPageID",
+			postID: post.ID,
+			query:  fmt.Sprintf("page_id=0&page_size=%d", n),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPostRevisions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			postID: post.ID,
+			query:  fmt.Sprintf("page_id=1&page_size=%d", n),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					ListPostRevisions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.PostRevision{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/posts/%d/revisions?%s", tc.postID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestGetPostRevisionAPI(t *testing.T) {
+	post := randomPost(util.RandomOwner())
+	revision := randomRevision(post, 1)
+
+	testCases := []struct {
+		name          string
+		revision      int32
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			revision: revision.Revision,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.GetPostRevisionParams{
+					PostID:   post.ID,
+					Revision: revision.Revision,
+				}
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(revision, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRevision(t, recorder.Body, revision)
+			},
+		},
+		{
+			name:     "NotFound",
+			revision: revision.Revision,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PostRevision{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidRevision",
+			revision: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/posts/%d/revisions/%d", post.ID, tc.revision)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDiffPostAPI(t *testing.T) {
+	post := randomPost(util.RandomOwner())
+	post.Content = "first\nsecond\nthird"
+
+	from := randomRevision(post, 1)
+	from.Content = "first\nthird"
+
+	to := randomRevision(post, 2)
+	to.Content = "first\nsecond"
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "from=1&to=2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: post.ID, Revision: 1})).
+					Times(1).
+					Return(from, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: post.ID, Revision: 2})).
+					Times(1).
+					Return(to, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				diff := requireBodyDiff(t, recorder.Body)
+				require.Equal(t, int32(1), diff.From)
+				require.Equal(t, int32(2), diff.To)
+				require.Equal(t, "--- revision 1\n+++ revision 2\n@@ -1,2 +1,2 @@\n first\n-third\n+second\n", diff.Diff)
+			},
+		},
+		{
+			name:  "CurrentVersion",
+			query: "from=1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: post.ID, Revision: 1})).
+					Times(1).
+					Return(from, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				diff := requireBodyDiff(t, recorder.Body)
+				require.Zero(t, diff.To)
+				require.Equal(t, "--- revision 1\n+++ current\n@@ -1,2 +1,3 @@\n first\n+second\n third\n", diff.Diff)
+			},
+		},
+		{
+			name:  "RevisionNotFound",
+			query: "from=1&to=3",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: post.ID, Revision: 1})).
+					Times(1).
+					Return(from, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: post.ID, Revision: 3})).
+					Times(1).
+					Return(db.PostRevision{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingFrom",
+			query: "to=2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/posts/%d/diff?%s", post.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRevertPostAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	post := randomPost(user.UserName)
+	revision := randomRevision(post, 1)
+
+	reverted := post
+	reverted.Image = revision.Image
+	reverted.Title = revision.Title
+	reverted.Subtitle = revision.Subtitle
+	reverted.Content = revision.Content
+
+	arg := db.PartialUpdatePostParams{
+		ID:       post.ID,
+		Image:    sql.NullString{String: revision.Image, Valid: true},
+		Title:    sql.NullString{String: revision.Title, Valid: true},
+		Subtitle: sql.NullString{String: revision.Subtitle, Valid: true},
+		Content:  sql.NullString{String: revision.Content, Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: post.ID, Revision: 1})).
+					Times(1).
+					Return(revision, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdatePostTxResult{Post: reverted}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchPost(t, recorder.Body, reverted)
+			},
+		},
+		{
+			name: "Moderator",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "moderator", util.ModeratorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(revision, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdatePostTxResult{Post: reverted}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized", util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "TitleTaken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(revision, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdatePostTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyError(t, recorder.Body, errPostTitleTaken)
+			},
+		},
+		{
+			name: "RevisionNotFound",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PostRevision{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.UserName, util.UserRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(revision, nil)
+				store.EXPECT().
+					UpdatePostTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdatePostTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/posts/%d/revisions/%d/revert", post.ID, revision.Revision)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func randomRevision(post db.Post, revision int32) db.PostRevision {
+	return db.PostRevision{
+		ID:        util.RandomInt(1, 1000),
+		PostID:    post.ID,
+		Revision:  revision,
+		Image:     util.RandomImage(),
+		Title:     util.RandomTitle(),
+		Subtitle:  util.RandomSubtitle(),
+		Content:   util.RandomContent(),
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+}
+
+func requireBodyMatchRevision(t *testing.T, body *bytes.Buffer, revision db.PostRevision) {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	var gotRevision db.PostRevision
+	err := json.NewDecoder(body).Decode(&gotRevision)
+	require.NoError(t, err)
+	require.Equal(t, revision.PostID, gotRevision.PostID)
+	require.Equal(t, revision.Revision, gotRevision.Revision)
+	require.Equal(t, revision.Content, gotRevision.Content)
+}
+
+func requireBodyDiff(t *testing.T, body *bytes.Buffer) diffPostResponse {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	var diff diffPostResponse
+	err := json.NewDecoder(body).Decode(&diff)
+	require.NoError(t, err)
+
+	return diff
+}
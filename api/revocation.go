@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/google/uuid"
+)
+
+// How often the in-memory revocation list is synced with the DB
+const revocationSyncInterval = time.Minute
+
+// revocationList is an in-memory cache in front of the revoked_tokens table.
+// authMiddleware only consults the cache, which is loaded on startup and then
+// periodically reloaded so revocations made by other instances are picked up.
+type revocationList struct {
+	store   db.Store
+	mu      sync.RWMutex
+	revoked map[uuid.UUID]time.Time
+}
+
+func newRevocationList(store db.Store) *revocationList {
+	return &revocationList{
+		store:   store,
+		revoked: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// revoke stores the token ID in the DB and the cache until the token expires
+func (list *revocationList) revoke(ctx context.Context, payload *token.Payload) error {
+	err := list.store.RevokeToken(ctx, db.RevokeTokenParams{
+		ID:        payload.ID,
+		UserName:  payload.UserName,
+		ExpiredAt: payload.ExpiredAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	list.add(payload.ID, payload.ExpiredAt)
+	return nil
+}
+
+func (list *revocationList) add(id uuid.UUID, expiredAt time.Time) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	list.revoked[id] = expiredAt
+}
+
+// isRevoked checks if the token with the given payload ID has been revoked
+func (list *revocationList) isRevoked(id uuid.UUID) bool {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+
+	_, ok := list.revoked[id]
+	return ok
+}
+
+// load replaces the cache with the unexpired revocations stored in the DB
+func (list *revocationList) load(ctx context.Context) error {
+	tokens, err := list.store.ListRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	revoked := make(map[uuid.UUID]time.Time, len(tokens))
+	for _, token := range tokens {
+		revoked[token.ID] = token.ExpiredAt
+	}
+
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	list.revoked = revoked
+	return nil
+}
+
+// prune drops the revocations of tokens that have expired by now,
+// since an expired token is rejected by VerifyToken anyway
+func (list *revocationList) prune(ctx context.Context) error {
+	now := time.Now()
+
+	list.mu.Lock()
+	for id, expiredAt := range list.revoked {
+		if now.After(expiredAt) {
+			delete(list.revoked, id)
+		}
+	}
+	list.mu.Unlock()
+
+	return list.store.DeleteExpiredRevokedTokens(ctx)
+}
+
+// sync prunes expired revocations and reloads the cache every interval until ctx is done
+func (list *revocationList) sync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := list.prune(ctx); err != nil {
+				log.Println("cannot prune revoked tokens:", err)
+			}
+			if err := list.load(ctx); err != nil {
+				log.Println("cannot load revoked tokens:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationListLoad(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	revoked := db.RevokedToken{
+		ID:        uuid.New(),
+		UserName:  "user",
+		ExpiredAt: time.Now().Add(time.Minute),
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListRevokedTokens(gomock.Any()).
+		Times(1).
+		Return([]db.RevokedToken{revoked}, nil)
+
+	list := newRevocationList(store)
+	stale := uuid.New()
+	list.add(stale, time.Now().Add(time.Minute))
+
+	err := list.load(context.Background())
+	require.NoError(t, err)
+
+	require.True(t, list.isRevoked(revoked.ID))
+	require.False(t, list.isRevoked(stale))
+}
+
+func TestRevocationListLoadError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListRevokedTokens(gomock.Any()).
+		Times(1).
+		Return([]db.RevokedToken{}, sql.ErrConnDone)
+
+	list := newRevocationList(store)
+	revoked := uuid.New()
+	list.add(revoked, time.Now().Add(time.Minute))
+
+	err := list.load(context.Background())
+	require.Error(t, err)
+
+	// A failed reload keeps the revocations that were already cached
+	require.True(t, list.isRevoked(revoked))
+}
+
+func TestRevocationListPrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredRevokedTokens(gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	list := newRevocationList(store)
+
+	expired := uuid.New()
+	active := uuid.New()
+	list.add(expired, time.Now().Add(-time.Minute))
+	list.add(active, time.Now().Add(time.Minute))
+
+	err := list.prune(context.Background())
+	require.NoError(t, err)
+
+	require.False(t, list.isRevoked(expired))
+	require.True(t, list.isRevoked(active))
+}
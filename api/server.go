@@ -1,34 +1,90 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/mail"
+	"github.com/CM-IV/mef-api/oidc"
 	"github.com/CM-IV/mef-api/token"
 	"github.com/CM-IV/mef-api/util"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
+//Requests in flight and background tasks get this long to finish when the server stops
+const shutdownTimeout = 30 * time.Second
+
 //Serves HTTP Requests for Posts
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config         util.Config
+	store          db.Store
+	tokenMaker     token.Maker
+	twoFactorMaker token.Maker
+	revocations    *revocationList
+	generations    *generationList
+	loginGuard     *loginGuard
+	limiter        *rateLimiter
+	mailer         mail.Sender
+	oidcProviders  map[string]*oidc.Provider
+	oidcLogins     *oidcLoginStore
+	tasks          sync.WaitGroup
+	router         *gin.Engine
 }
 
 //Create new HTTP Server and setup routes
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	twoFactorMaker, err := newTwoFactorMaker(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create two-factor token maker: %w", err)
+	}
+
+	mailer, err := mail.NewSender(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mailer: %w", err)
+	}
+
+	limiter, err := newRateLimiter(config, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limiter: %w", err)
+	}
+
+	oidcProviders, err := newOIDCProviders(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create OIDC providers: %w", err)
+	}
+
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:         config,
+		store:          store,
+		tokenMaker:     tokenMaker,
+		twoFactorMaker: twoFactorMaker,
+		revocations:    newRevocationList(store),
+		generations:    newGenerationList(store),
+		loginGuard:     newLoginGuard(config),
+		limiter:        limiter,
+		mailer:         mailer,
+		oidcProviders:  oidcProviders,
+		oidcLogins:     newOIDCLoginStore(config),
+	}
+
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation("slug", validSlug)
 	}
 
 	server.setupRouter()
@@ -40,32 +96,96 @@ func (server *Server) setupRouter() {
 	router := gin.Default()
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
-		AllowMethods:     []string{"PUT", "POST", "HEAD", "DELETE", "OPTIONS", "GET"},
+		AllowMethods:     []string{"PUT", "PATCH", "POST", "HEAD", "DELETE", "OPTIONS", "GET"},
 		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 	}))
 
 	router.SetTrustedProxies(nil)
 
 	//API GROUP
+	accountLimit := server.limiter.limit(rateLimitAccount)
+	postingLimit := server.limiter.limit(rateLimitPosting)
+	postsWrite := requireScopes(util.PostsWriteScope)
+	commentsWrite := requireScopes(util.CommentsWriteScope)
+	account := requireScopes(util.AccountScope)
+	usersAdmin := requireScopes(util.UsersAdminScope)
+	categoriesAdmin := requireScopes(util.CategoriesAdminScope)
+
 	api := router.Group("/api")
+	api.Use(server.limiter.limit(rateLimitPublic))
 	{
+		api.GET("/posts/search", server.searchPosts)
 		api.GET("/posts/:id", server.getPost)
 		api.GET("/posts", server.listPost)
+		api.GET("/posts/:id/comments", server.listComments)
+		api.GET("/posts/:id/revisions", server.listPostRevisions)
+		api.GET("/posts/:id/revisions/:rev", server.getPostRevision)
+		api.GET("/posts/:id/diff", server.diffPost)
+		api.GET("/categories", server.listCategories)
+		api.GET("/categories/:id", server.getCategory)
+		api.GET("/tags", server.listTags)
+		api.GET("/.well-known/paseto-keys", server.listTokenKeys)
+		api.GET("/.well-known/jwks.json", server.listJSONWebKeys)
 
 		//USERS ENDPOINTS
-		api.POST("/users", server.createUser)
-		api.POST("/users/login", server.loginUser)
+		api.POST("/users", accountLimit, server.createUser)
+		api.POST("/users/login", accountLimit, server.loginUser)
+		api.POST("/users/login/2fa", accountLimit, server.loginTwoFactor)
+		api.GET("/users/verify_email", accountLimit, server.verifyEmail)
+		api.POST("/users/password/forgot", accountLimit, server.forgotPassword)
+		api.POST("/users/password/reset", accountLimit, server.resetPassword)
+		api.GET("/users/:user_name", server.getUserProfile)
 
-		authRoutes := router.Group("/api")
-		authRoutes.Use(authMiddleware(server.tokenMaker))
+		//OIDC ENDPOINTS
+		api.GET("/auth/oidc/:provider/start", accountLimit, server.startOIDCLogin)
+		api.GET("/auth/oidc/:provider/callback", accountLimit, server.finishOIDCLogin)
+
+		//TOKENS ENDPOINTS
+		api.POST("/tokens/renew_access", server.renewAccessToken)
+
+		//Nested in the api group so the per-IP limit runs before authentication,
+		//the per-user limit after it
+		authRoutes := api.Group("")
+		authRoutes.Use(authMiddleware(server.tokenMaker, server.revocations, server.generations, server.store), server.limiter.limit(rateLimitUser))
 		{
 			//PROTECTED ENDPOINTS
+			//USERS ENDPOINTS
+			authRoutes.POST("/users/logout", server.logoutUser)
+			authRoutes.PUT("/users/:user_name/role", requireRole(util.AdminRole), usersAdmin, server.updateUserRole)
+			authRoutes.GET("/users", requireRole(util.AdminRole), usersAdmin, server.listUsers)
+			authRoutes.GET("/users/lockouts", requireRole(util.AdminRole), usersAdmin, server.listLoginLockouts)
+			authRoutes.DELETE("/users/:user_name/lockout", requireRole(util.AdminRole), usersAdmin, server.unlockUser)
+			authRoutes.GET("/users/me", server.getMe)
+			authRoutes.PATCH("/users/me", account, server.updateMe)
+			authRoutes.PUT("/users/me/password", account, server.changePassword)
+			authRoutes.DELETE("/users/me", account, server.deleteMe)
+			authRoutes.POST("/users/me/2fa/setup", account, server.setupTwoFactor)
+			authRoutes.POST("/users/me/2fa/enable", account, server.enableTwoFactor)
+			authRoutes.GET("/users/me/trash", server.listTrash)
+			authRoutes.POST("/users/me/api_keys", account, server.createAPIKey)
+			authRoutes.GET("/users/me/api_keys", account, server.listAPIKeys)
+			authRoutes.DELETE("/users/me/api_keys/:id", account, server.deleteAPIKey)
+			authRoutes.POST("/users/me/identities/:provider", account, server.startOIDCLink)
+
 			//POSTS ENDPOINTS
-			authRoutes.PUT("/posts/:id", server.updatePost)
-			authRoutes.DELETE("/posts/:id", server.deletePost)
-			authRoutes.POST("/posts", server.createPost)
+			authRoutes.PUT("/posts/:id", postsWrite, server.updatePost)
+			authRoutes.PATCH("/posts/:id", postsWrite, server.patchPost)
+			authRoutes.DELETE("/posts/:id", postsWrite, server.deletePost)
+			authRoutes.POST("/posts/:id/restore", postsWrite, server.restorePost)
+			authRoutes.POST("/posts/:id/revisions/:rev/revert", postsWrite, server.revertPost)
+			authRoutes.POST("/posts", postsWrite, postingLimit, server.createPost)
+
+			//COMMENTS ENDPOINTS
+			authRoutes.POST("/posts/:id/comments", commentsWrite, postingLimit, server.createComment)
+			authRoutes.PUT("/comments/:id", commentsWrite, server.updateComment)
+			authRoutes.DELETE("/comments/:id", commentsWrite, server.deleteComment)
+
+			//CATEGORIES ENDPOINTS
+			authRoutes.POST("/categories", requireRole(util.AdminRole), categoriesAdmin, server.createCategory)
+			authRoutes.PUT("/categories/:id", requireRole(util.AdminRole), categoriesAdmin, server.updateCategory)
+			authRoutes.DELETE("/categories/:id", requireRole(util.AdminRole), categoriesAdmin, server.deleteCategory)
 		}
 
 	}
@@ -73,10 +193,68 @@ func (server *Server) setupRouter() {
 	server.router = router
 }
 
-//Start runs HTTP Server on a specific address
+//Start runs HTTP Server on a specific address until the process is interrupted or terminated
 func (server *Server) Start(address string) error {
 
-	return server.router.Run(address)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := server.revocations.load(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot load revoked tokens: %w", err)
+	}
+	go server.revocations.sync(ctx, revocationSyncInterval)
+
+	err = server.generations.load(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot load token generations: %w", err)
+	}
+	go server.generations.sync(ctx, generationSyncInterval)
+	go server.loginGuard.expire(ctx, loginAttemptPruneInterval)
+	go server.limiter.expire(ctx, rateLimitPruneInterval)
+	go server.purgeDeletedPosts(ctx, purgeInterval)
+
+	httpServer := &http.Server{Addr: address, Handler: server.router}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	return server.shutdown(httpServer, shutdownTimeout)
+
+}
+
+//shutdown stops taking requests, then waits for the ones in flight and for background tasks
+//such as password reset emails, which would be lost otherwise
+func (server *Server) shutdown(httpServer *http.Server, timeout time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := httpServer.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot shut down server: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("background tasks did not finish before the shutdown timeout")
+	}
 
 }
 
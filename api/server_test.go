@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownWaitsForTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	httpServer := &http.Server{Handler: server.router}
+
+	finished := false
+	server.tasks.Add(1)
+	go func() {
+		defer server.tasks.Done()
+		time.Sleep(50 * time.Millisecond)
+		finished = true
+	}()
+
+	err := server.shutdown(httpServer, time.Minute)
+	require.NoError(t, err)
+	require.True(t, finished)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	httpServer := &http.Server{Handler: server.router}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	server.tasks.Add(1)
+	go func() {
+		defer server.tasks.Done()
+		<-release
+	}()
+
+	err := server.shutdown(httpServer, 50*time.Millisecond)
+	require.Error(t, err)
+}
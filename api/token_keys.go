@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of access and refresh tokens the server issues
+const (
+	tokenTypePaseto       = "paseto"
+	tokenTypePasetoPublic = "paseto-public"
+	tokenTypeJWT          = "jwt"
+	tokenTypeJWTPublic    = "jwt-public"
+)
+
+var errTokenKeysNotPublic = errors.New("tokens are not signed with public keys")
+
+// newTokenMaker creates the maker selected by the config, symmetric v2.local
+// tokens unless asked otherwise. The public kinds sign with the key ring.
+func newTokenMaker(config util.Config) (token.Maker, error) {
+	options := tokenOptions(config)
+
+	switch config.TokenType {
+	case "", tokenTypePaseto:
+		return token.NewPasetoMaker(config.TokenSymmetricKey, options)
+	case tokenTypePasetoPublic:
+		keys, err := token.ParseKeyRing(config.TokenPrivateKeys, config.TokenPublicKeys)
+		if err != nil {
+			return nil, err
+		}
+		return token.NewPasetoPublicMaker(keys, options)
+	case tokenTypeJWT:
+		return token.NewJWTMaker(config.TokenSymmetricKey, options)
+	case tokenTypeJWTPublic:
+		keys, err := token.ParseKeyRing(config.TokenPrivateKeys, config.TokenPublicKeys)
+		if err != nil {
+			return nil, err
+		}
+		return token.NewJWTPublicMaker(keys, options)
+	default:
+		return nil, fmt.Errorf("unsupported token type %q", config.TokenType)
+	}
+}
+
+// The issuer and audience of the tokens of the server
+func tokenOptions(config util.Config) token.Options {
+	return token.Options{
+		Issuer:    config.TokenIssuer,
+		Audience:  config.TokenAudience,
+		ClockSkew: config.TokenClockSkew,
+	}
+}
+
+type tokenKeyResponse struct {
+	KeyID     string `json:"kid"`
+	Version   string `json:"version"`
+	Purpose   string `json:"purpose"`
+	PublicKey string `json:"public_key"`
+}
+
+type listTokenKeysResponse struct {
+	Keys []tokenKeyResponse `json:"keys"`
+}
+
+// listTokenKeys publishes the keys that verify the tokens, the key ID is in the token footer.
+// Keys are encoded like the tokens themselves, in unpadded base64url.
+func (server *Server) listTokenKeys(ctx *gin.Context) {
+	maker, ok := server.tokenMaker.(*token.PasetoPublicMaker)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errorResponse(errTokenKeysNotPublic))
+		return
+	}
+
+	rsp := listTokenKeysResponse{Keys: []tokenKeyResponse{}}
+	for _, key := range maker.PublicKeys() {
+		rsp.Keys = append(rsp.Keys, tokenKeyResponse{
+			KeyID:     key.ID,
+			Version:   "v2",
+			Purpose:   "public",
+			PublicKey: base64.RawURLEncoding.EncodeToString(key.Key),
+		})
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type jsonWebKeyResponse struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+type listJSONWebKeysResponse struct {
+	Keys []jsonWebKeyResponse `json:"keys"`
+}
+
+// listJSONWebKeys publishes the keys that verify EdDSA JWTs as a JWK set (RFC 8037)
+func (server *Server) listJSONWebKeys(ctx *gin.Context) {
+	maker, ok := server.tokenMaker.(*token.JWTMaker)
+	if !ok || len(maker.PublicKeys()) == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(errTokenKeysNotPublic))
+		return
+	}
+
+	rsp := listJSONWebKeysResponse{Keys: []jsonWebKeyResponse{}}
+	for _, key := range maker.PublicKeys() {
+		rsp.Keys = append(rsp.Keys, jsonWebKeyResponse{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.Key),
+			KeyID:     key.ID,
+			Algorithm: token.AlgorithmEdDSA,
+			Use:       "sig",
+		})
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	"github.com/CM-IV/mef-api/mail"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/golang/mock/gomock"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func randomTokenKey(t *testing.T) (string, ed25519.PublicKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(privateKey.Seed()), publicKey
+}
+
+func TestListTokenKeys(t *testing.T) {
+	currentSeed, currentKey := randomTokenKey(t)
+	previousSeed, previousKey := randomTokenKey(t)
+	_, retiredKey := randomTokenKey(t)
+
+	testCases := []struct {
+		name          string
+		buildConfig   func(config *util.Config)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildConfig: func(config *util.Config) {
+				config.TokenType = tokenTypePasetoPublic
+				config.TokenPrivateKeys = "current:" + currentSeed + ",previous:" + previousSeed
+				config.TokenPublicKeys = "retired:" + base64.StdEncoding.EncodeToString(retiredKey)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "public, max-age=300", recorder.Header().Get("Cache-Control"))
+
+				var rsp listTokenKeysResponse
+				err := jsoniter.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+
+				require.Equal(t, []tokenKeyResponse{
+					{KeyID: "current", Version: "v2", Purpose: "public", PublicKey: base64.RawURLEncoding.EncodeToString(currentKey)},
+					{KeyID: "previous", Version: "v2", Purpose: "public", PublicKey: base64.RawURLEncoding.EncodeToString(previousKey)},
+					{KeyID: "retired", Version: "v2", Purpose: "public", PublicKey: base64.RawURLEncoding.EncodeToString(retiredKey)},
+				}, rsp.Keys)
+
+				// The published key verifies the tokens of the server
+				accessToken, _, err := server.tokenMaker.CreateToken("user", util.UserRole, 0, util.RoleScopes(util.UserRole), time.Minute)
+				require.NoError(t, err)
+
+				ring, err := token.NewKeyRing("other", ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+				require.NoError(t, err)
+				publicKey, err := base64.RawURLEncoding.DecodeString(rsp.Keys[0].PublicKey)
+				require.NoError(t, err)
+				require.NoError(t, ring.AddPublicKey(rsp.Keys[0].KeyID, publicKey))
+
+				verifier, err := token.NewPasetoPublicMaker(ring, token.Options{})
+				require.NoError(t, err)
+				payload, err := verifier.VerifyToken(accessToken)
+				require.NoError(t, err)
+				require.Equal(t, "user", payload.UserName)
+			},
+		},
+		{
+			name: "SymmetricTokens",
+			buildConfig: func(config *util.Config) {
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireBodyError(t, recorder.Body, errTokenKeysNotPublic)
+			},
+		},
+		{
+			name: "JWTs",
+			buildConfig: func(config *util.Config) {
+				config.TokenType = tokenTypeJWTPublic
+				config.TokenPrivateKeys = "current:" + currentSeed
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireBodyError(t, recorder.Body, errTokenKeysNotPublic)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server, recorder := serveTokenKeys(t, tc.buildConfig, "/api/.well-known/paseto-keys")
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+func TestListJSONWebKeys(t *testing.T) {
+	currentSeed, currentKey := randomTokenKey(t)
+	_, retiredKey := randomTokenKey(t)
+
+	testCases := []struct {
+		name          string
+		buildConfig   func(config *util.Config)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildConfig: func(config *util.Config) {
+				config.TokenType = tokenTypeJWTPublic
+				config.TokenPrivateKeys = "current:" + currentSeed
+				config.TokenPublicKeys = "retired:" + base64.StdEncoding.EncodeToString(retiredKey)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "public, max-age=300", recorder.Header().Get("Cache-Control"))
+
+				var rsp listJSONWebKeysResponse
+				err := jsoniter.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+
+				require.Equal(t, []jsonWebKeyResponse{
+					{KeyType: "OKP", Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(currentKey), KeyID: "current", Algorithm: "EdDSA", Use: "sig"},
+					{KeyType: "OKP", Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(retiredKey), KeyID: "retired", Algorithm: "EdDSA", Use: "sig"},
+				}, rsp.Keys)
+			},
+		},
+		{
+			name: "HS256",
+			buildConfig: func(config *util.Config) {
+				config.TokenType = tokenTypeJWT
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireBodyError(t, recorder.Body, errTokenKeysNotPublic)
+			},
+		},
+		{
+			name: "PasetoTokens",
+			buildConfig: func(config *util.Config) {
+				config.TokenType = tokenTypePasetoPublic
+				config.TokenPrivateKeys = "current:" + currentSeed
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireBodyError(t, recorder.Body, errTokenKeysNotPublic)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, recorder := serveTokenKeys(t, tc.buildConfig, "/api/.well-known/jwks.json")
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func serveTokenKeys(t *testing.T, buildConfig func(config *util.Config), url string) (*Server, *httptest.ResponseRecorder) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	config := util.Config{
+		TokenSymmetricKey:     util.RandomString(32),
+		AccessTokenDuration:   time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		EmailSender:           mail.MemorySenderKind,
+		TwoFactorSymmetricKey: util.RandomString(32),
+	}
+	buildConfig(&config)
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	return server, recorder
+}
+
+func TestNewTokenMaker(t *testing.T) {
+	seed, _ := randomTokenKey(t)
+
+	testCases := []struct {
+		name      string
+		config    util.Config
+		checkType func(t *testing.T, maker token.Maker, err error)
+	}{
+		{
+			name:   "DefaultsToPaseto",
+			config: util.Config{TokenSymmetricKey: util.RandomString(32)},
+			checkType: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.PasetoMaker{}, maker)
+			},
+		},
+		{
+			name:   "PasetoPublic",
+			config: util.Config{TokenType: tokenTypePasetoPublic, TokenPrivateKeys: "k1:" + seed},
+			checkType: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.PasetoPublicMaker{}, maker)
+			},
+		},
+		{
+			name:   "PasetoPublicWithoutKeys",
+			config: util.Config{TokenType: tokenTypePasetoPublic},
+			checkType: func(t *testing.T, maker token.Maker, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:   "JWT",
+			config: util.Config{TokenType: tokenTypeJWT, TokenSymmetricKey: util.RandomString(32)},
+			checkType: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.JWTMaker{}, maker)
+				require.Empty(t, maker.(*token.JWTMaker).PublicKeys())
+			},
+		},
+		{
+			name:   "JWTPublic",
+			config: util.Config{TokenType: tokenTypeJWTPublic, TokenPrivateKeys: "k1:" + seed},
+			checkType: func(t *testing.T, maker token.Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.JWTMaker{}, maker)
+				require.Len(t, maker.(*token.JWTMaker).PublicKeys(), 1)
+			},
+		},
+		{
+			name:   "JWTShortKey",
+			config: util.Config{TokenType: tokenTypeJWT, TokenSymmetricKey: util.RandomString(16)},
+			checkType: func(t *testing.T, maker token.Maker, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:   "UnsupportedType",
+			config: util.Config{TokenType: "unsupported"},
+			checkType: func(t *testing.T, maker token.Maker, err error) {
+				require.EqualError(t, err, `unsupported token type "unsupported"`)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := newTokenMaker(tc.config)
+			tc.checkType(t, maker, err)
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	//Number of recovery codes handed out when two-factor authentication is enabled
+	recoveryCodeCount = 10
+	//Number of random bytes in a recovery code
+	recoveryCodeSize = 10
+)
+
+var (
+	errTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	errTwoFactorNotSetUp    = errors.New("two-factor authentication has not been set up")
+	errInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+//newTwoFactorMaker creates the maker of the intermediate tokens of a two-step login.
+//It has a key of its own, whatever TOKEN_TYPE is, so its tokens are never accepted as access tokens
+//and cannot be forged when the access tokens are signed with a public key pair.
+func newTwoFactorMaker(config util.Config) (token.Maker, error) {
+	if len(config.TwoFactorSymmetricKey) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("TWO_FACTOR_SYMMETRIC_KEY must be exactly %d characters", chacha20poly1305.KeySize)
+	}
+	if config.TwoFactorSymmetricKey == config.TokenSymmetricKey {
+		return nil, errors.New("TWO_FACTOR_SYMMETRIC_KEY must differ from TOKEN_SYMMETRIC_KEY")
+	}
+
+	return token.NewPasetoMaker(config.TwoFactorSymmetricKey, tokenOptions(config))
+}
+
+type twoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+//setupTwoFactor generates a new TOTP secret, it is only used once confirmed with enableTwoFactor
+func (server *Server) setupTwoFactor(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, ok := server.getTwoFactorUser(ctx, authPayload.UserName)
+	if !ok {
+		return
+	}
+
+	secret, err := util.NewTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = server.store.SetUserTOTPSecret(ctx, db.SetUserTOTPSecretParams{
+		UserName:   user.UserName,
+		TotpSecret: secret,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusForbidden, errorResponse(errTwoFactorEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, twoFactorSetupResponse{
+		Secret:     user.TotpSecret,
+		OTPAuthURI: util.TOTPURI(server.config.TOTPIssuer, user.UserName, user.TotpSecret),
+	})
+}
+
+type enableTwoFactorRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type enableTwoFactorResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+//enableTwoFactor turns on two-factor authentication once the user proves the app has the secret.
+//The recovery codes are only shown in this response, just their hashes are stored.
+func (server *Server) enableTwoFactor(ctx *gin.Context) {
+	var req enableTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, ok := server.getTwoFactorUser(ctx, authPayload.UserName)
+	if !ok {
+		return
+	}
+
+	if user.TotpSecret == "" {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTwoFactorNotSetUp))
+		return
+	}
+
+	step, ok := util.ValidateTOTP(user.TotpSecret, req.Code, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidTwoFactorCode))
+		return
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		codes[i] = code
+		hashes[i] = util.HashToken(code)
+	}
+
+	_, err := server.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		UserName:           user.UserName,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enableTwoFactorResponse{RecoveryCodes: codes})
+}
+
+//getTwoFactorUser fetches the user that manages their two-factor authentication,
+//the error response is already written when false is returned
+func (server *Server) getTwoFactorUser(ctx *gin.Context, userName string) (db.User, bool) {
+	user, err := server.store.GetUser(ctx, userName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return user, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return user, false
+	}
+
+	if user.IsTotpEnabled {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTwoFactorEnabled))
+		return user, false
+	}
+
+	return user, true
+}
+
+type twoFactorRequiredResponse struct {
+	TwoFactorRequired       bool      `json:"two_factor_required"`
+	TwoFactorToken          string    `json:"two_factor_token"`
+	TwoFactorTokenExpiresAt time.Time `json:"two_factor_token_expires_at"`
+}
+
+//requireTwoFactor answers a correct password with the intermediate token that loginTwoFactor exchanges for a session
+func (server *Server) requireTwoFactor(ctx *gin.Context, user db.User) {
+	twoFactorToken, payload, err := server.twoFactorMaker.CreateToken(
+		user.UserName,
+		user.Role,
+		user.TokenGeneration,
+		nil,
+		server.config.TwoFactorTokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, twoFactorRequiredResponse{
+		TwoFactorRequired:       true,
+		TwoFactorToken:          twoFactorToken,
+		TwoFactorTokenExpiresAt: payload.ExpiredAt,
+	})
+}
+
+type loginTwoFactorRequest struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	Code           string `json:"code" binding:"required,max=32"`
+}
+
+//loginTwoFactor is the second step of the login, it takes a TOTP or a recovery code
+func (server *Server) loginTwoFactor(ctx *gin.Context) {
+	var req loginTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := server.twoFactorMaker.VerifyToken(req.TwoFactorToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	clientIP := ctx.ClientIP()
+	if server.isLoginLockedOut(ctx, payload.UserName, clientIP) {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.UserName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The password may have been reset since the first step
+	if !user.IsTotpEnabled || payload.Generation < user.TokenGeneration {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	ok, err := server.checkTwoFactorCode(ctx, user, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		server.loginGuard.fail(user.UserName, clientIP)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidTwoFactorCode))
+		return
+	}
+
+	server.loginGuard.succeed(user.UserName)
+	server.startSession(ctx, user)
+}
+
+//checkTwoFactorCode accepts a TOTP code that has not been used yet or an unused recovery code
+func (server *Server) checkTwoFactorCode(ctx *gin.Context, user db.User, code string) (bool, error) {
+	if step, ok := util.ValidateTOTP(user.TotpSecret, code, time.Now()); ok {
+		used, err := server.store.UseUserTOTPStep(ctx, db.UseUserTOTPStepParams{
+			UserName:     user.UserName,
+			TotpLastStep: step,
+		})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	_, err := server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UserName: user.UserName,
+		CodeHash: util.HashToken(normalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+//newRecoveryCode returns a random code that is easy to type, e.g. mzxw6ytboi4dgnzq
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(recoveryCodeEncoding.EncodeToString(b)), nil
+}
+
+//Recovery codes are accepted in any case and with the spaces or dashes people add when writing them down
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
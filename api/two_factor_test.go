@@ -0,0 +1,648 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/CM-IV/mef-api/db/mock"
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/mail"
+	"github.com/CM-IV/mef-api/token"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func randomTwoFactorUser(t *testing.T) (user db.User, password string) {
+	user, password = randomUser(t)
+
+	secret, err := util.NewTOTPSecret()
+	require.NoError(t, err)
+
+	user.TotpSecret = secret
+	user.IsTotpEnabled = true
+	return
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := util.TOTPCode(secret, time.Now())
+	require.NoError(t, err)
+	return code
+}
+
+func TestSetupTwoFactorAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	enabledUser, _ := randomTwoFactorUser(t)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: user,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.SetUserTOTPSecretParams) (db.User, error) {
+						require.Equal(t, user.UserName, arg.UserName)
+						require.Len(t, arg.TotpSecret, 32)
+
+						updated := user
+						updated.TotpSecret = arg.TotpSecret
+						return updated, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp twoFactorSetupResponse
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Secret, 32)
+				require.Contains(t, rsp.OTPAuthURI, "otpauth://totp/MEF:"+user.UserName+"?")
+				require.Contains(t, rsp.OTPAuthURI, "secret="+rsp.Secret)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			user: enabledUser,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(enabledUser.UserName)).
+					Times(1).
+					Return(enabledUser, nil)
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "EnabledConcurrently",
+			user: user,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					SetUserTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			user: user,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.TOTPIssuer = "MEF"
+			recorder := httptest.NewRecorder()
+
+			url := "/api/users/me/2fa/setup"
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.UserName, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestEnableTwoFactorAPI(t *testing.T) {
+	user, _ := randomTwoFactorUser(t)
+	user.IsTotpEnabled = false
+
+	notSetUp, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: user,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.EnableTOTPTxParams) (db.EnableTOTPTxResult, error) {
+						require.Equal(t, user.UserName, arg.UserName)
+						require.InDelta(t, util.TOTPStep(time.Now()), arg.Step, 1)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+
+						enabled := user
+						enabled.IsTotpEnabled = true
+						return db.EnableTOTPTxResult{User: enabled}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp enableTwoFactorResponse
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+				for _, code := range rsp.RecoveryCodes {
+					require.Len(t, code, 16)
+				}
+			},
+		},
+		{
+			name: "WrongCode",
+			user: user,
+			code: func(t *testing.T) string {
+				// A code from long ago is never valid now
+				code, err := util.TOTPCode(user.TotpSecret, time.Now().Add(-time.Hour))
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotSetUp",
+			user: notSetUp,
+			code: func(t *testing.T) string {
+				return "123456"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(notSetUp.UserName)).
+					Times(1).
+					Return(notSetUp, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			user: user,
+			code: func(t *testing.T) string {
+				return "12ab56"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			user: user,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EnableTOTPTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+			data, err := json.Marshal(gin.H{"code": tc.code(t)})
+			require.NoError(t, err)
+
+			url := "/api/users/me/2fa/enable"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.UserName, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestLoginUserTwoFactorRequired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, password := randomTwoFactorUser(t)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	server.config.TwoFactorTokenDuration = time.Minute
+	recorder := httptest.NewRecorder()
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	data, err := json.Marshal(gin.H{
+		"user_name": user.UserName,
+		"password":  password,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/api/users/login", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp twoFactorRequiredResponse
+	err = json.NewDecoder(recorder.Body).Decode(&rsp)
+	require.NoError(t, err)
+	require.True(t, rsp.TwoFactorRequired)
+	require.WithinDuration(t, time.Now().Add(time.Minute), rsp.TwoFactorTokenExpiresAt, time.Second)
+
+	payload, err := server.twoFactorMaker.VerifyToken(rsp.TwoFactorToken)
+	require.NoError(t, err)
+	require.Equal(t, user.UserName, payload.UserName)
+
+	// The intermediate token is no access token
+	_, err = server.tokenMaker.VerifyToken(rsp.TwoFactorToken)
+	require.Error(t, err)
+}
+
+func TestLoginTwoFactorAPI(t *testing.T) {
+	user, _ := randomTwoFactorUser(t)
+	recoveryCode, err := newRecoveryCode()
+	require.NoError(t, err)
+
+	twoFactorToken := func(t *testing.T, server *Server) string {
+		token, _, err := server.twoFactorMaker.CreateToken(user.UserName, user.Role, user.TokenGeneration, nil, time.Minute)
+		require.NoError(t, err)
+		return token
+	}
+
+	testCases := []struct {
+		name          string
+		setupToken    func(t *testing.T, server *Server) string
+		code          func(t *testing.T) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			setupToken: twoFactorToken,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UseUserTOTPStepParams) (int64, error) {
+						require.Equal(t, user.UserName, arg.UserName)
+						require.InDelta(t, util.TOTPStep(time.Now()), arg.TotpLastStep, 1)
+						return 1, nil
+					})
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+				var rsp loginUserResponse
+				err := json.NewDecoder(recorder.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.True(t, rsp.User.IsTotpEnabled)
+			},
+		},
+		{
+			name:       "ReplayedCode",
+			setupToken: twoFactorToken,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "RecoveryCode",
+			setupToken: twoFactorToken,
+			code: func(t *testing.T) string {
+				return "  " + recoveryCode[:8] + "-" + recoveryCode[8:]
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				arg := db.UseRecoveryCodeParams{
+					UserName: user.UserName,
+					CodeHash: util.HashToken(recoveryCode),
+				}
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.RecoveryCode{UserName: user.UserName, CodeHash: arg.CodeHash}, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "WrongCode",
+			setupToken: twoFactorToken,
+			code: func(t *testing.T) string {
+				return "not-a-code"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecoveryCode{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyError(t, recorder.Body, errInvalidTwoFactorCode)
+			},
+		},
+		{
+			name: "AccessToken",
+			setupToken: func(t *testing.T, server *Server) string {
+				token, _, err := server.tokenMaker.CreateToken(user.UserName, user.Role, user.TokenGeneration, util.RoleScopes(user.Role), time.Minute)
+				require.NoError(t, err)
+				return token
+			},
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordResetSinceFirstStep",
+			setupToken: func(t *testing.T, server *Server) string {
+				token, _, err := server.twoFactorMaker.CreateToken(user.UserName, user.Role, user.TokenGeneration-1, nil, time.Minute)
+				require.NoError(t, err)
+				return token
+			},
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "InternalError",
+			setupToken: twoFactorToken,
+			code: func(t *testing.T) string {
+				return currentTOTPCode(t, user.TotpSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.UserName)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+			data, err := json.Marshal(gin.H{
+				"two_factor_token": tc.setupToken(t, server),
+				"code":             tc.code(t),
+			})
+			require.NoError(t, err)
+
+			url := "/api/users/login/2fa"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestTwoFactorTokenIsNoAccessToken(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	request, err := http.NewRequest(http.MethodGet, "/api/users/me", nil)
+	require.NoError(t, err)
+
+	var maker token.Maker = server.twoFactorMaker
+	addAuthorization(t, request, maker, authorizationTypeBearer, "user", util.UserRole, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestNewTwoFactorMaker(t *testing.T) {
+	seed, _ := randomTokenKey(t)
+	key := util.RandomString(32)
+
+	testCases := []struct {
+		name   string
+		config util.Config
+		ok     bool
+	}{
+		{
+			name:   "OK",
+			config: util.Config{TokenSymmetricKey: util.RandomString(32), TwoFactorSymmetricKey: key},
+			ok:     true,
+		},
+		{
+			// Access tokens signed with a key pair need no symmetric key, the two-factor tokens still do
+			name:   "PublicTokens",
+			config: util.Config{TokenType: tokenTypePasetoPublic, TokenPrivateKeys: "k1:" + seed, TwoFactorSymmetricKey: key},
+			ok:     true,
+		},
+		{
+			name:   "MissingKey",
+			config: util.Config{TokenType: tokenTypePasetoPublic, TokenPrivateKeys: "k1:" + seed},
+		},
+		{
+			name:   "ShortKey",
+			config: util.Config{TokenSymmetricKey: util.RandomString(32), TwoFactorSymmetricKey: util.RandomString(16)},
+		},
+		{
+			name:   "SameKeyAsAccessTokens",
+			config: util.Config{TokenSymmetricKey: key, TwoFactorSymmetricKey: key},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := newTwoFactorMaker(tc.config)
+			if tc.ok {
+				require.NoError(t, err)
+				require.NotNil(t, maker)
+				return
+			}
+			require.Error(t, err)
+
+			tc.config.EmailSender = mail.MemorySenderKind
+			_, err = NewServer(tc.config, nil)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"regexp"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+//Lowercase words separated by single dashes, used by category slugs
+var validSlug validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if slug, ok := fieldLevel.Field().Interface().(string); ok {
+		return slugPattern.MatchString(slug)
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	db "github.com/CM-IV/mef-api/db/sqlc"
+	"github.com/CM-IV/mef-api/util"
+	"github.com/gin-gonic/gin"
+)
+
+//Number of random bytes in an email verification code
+const verifyEmailCodeSize = 32
+
+var errEmailNotVerified = errors.New("email address is not verified")
+
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"id" binding:"required,min=1"`
+	SecretCode string `form:"code" binding:"required,max=64"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    req.EmailID,
+		SecretCode: req.SecretCode,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := errors.New("invalid or expired verification code")
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+//newVerifyEmailCode returns a fresh secret code and the time it stops being accepted
+func (server *Server) newVerifyEmailCode() (string, time.Time, error) {
+	code, err := util.RandomSecret(verifyEmailCodeSize)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return code, time.Now().Add(server.config.VerifyEmailDuration), nil
+}
+
+//sendVerifyEmail mails the link that verifies the address of the user
+func (server *Server) sendVerifyEmail(user db.User, verifyEmail db.VerifyEmail) error {
+	link := fmt.Sprintf("%s/api/users/verify_email?id=%d&code=%s",
+		server.config.AppBaseURL,
+		verifyEmail.ID,
+		url.QueryEscape(verifyEmail.SecretCode),
+	)
+
+	subject := "Verify your email address"
+	content := fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires at %s.\n",
+		user.FullName,
+		link,
+		verifyEmail.ExpiredAt.Format(time.RFC1123),
+	)
+
+	return server.mailer.SendEmail(subject, content, []string{verifyEmail.Email})
+}
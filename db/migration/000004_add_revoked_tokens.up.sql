@@ -0,0 +1,10 @@
+CREATE TABLE "revoked_tokens" (
+  "id" uuid PRIMARY KEY,
+  "user_name" varchar NOT NULL,
+  "expired_at" timestamptz NOT NULL,
+  "revoked_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "revoked_tokens" ADD FOREIGN KEY ("user_name") REFERENCES "users" ("user_name");
+
+CREATE INDEX ON "revoked_tokens" ("expired_at");
@@ -0,0 +1,3 @@
+ALTER TABLE "posts" ADD COLUMN "updated_at" timestamptz NOT NULL DEFAULT (now());
+
+UPDATE "posts" SET "updated_at" = "created_at";
@@ -0,0 +1,19 @@
+CREATE TABLE "comments" (
+  "id" bigserial PRIMARY KEY,
+  "post_id" bigint NOT NULL,
+  "author" varchar NOT NULL,
+  "parent_id" bigint,
+  "body" text NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "comments" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "comments" ADD FOREIGN KEY ("author") REFERENCES "users" ("user_name");
+
+ALTER TABLE "comments" ADD FOREIGN KEY ("parent_id") REFERENCES "comments" ("id") ON DELETE CASCADE;
+
+CREATE INDEX ON "comments" ("post_id");
+
+CREATE INDEX ON "comments" ("parent_id");
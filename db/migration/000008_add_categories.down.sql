@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS "posts" DROP CONSTRAINT IF EXISTS "posts_category_id_fkey";
+
+ALTER TABLE IF EXISTS "posts" DROP COLUMN IF EXISTS "category_id";
+
+DROP TABLE IF EXISTS "categories";
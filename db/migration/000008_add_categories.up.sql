@@ -0,0 +1,13 @@
+CREATE TABLE "categories" (
+  "id" bigserial PRIMARY KEY,
+  "slug" varchar UNIQUE NOT NULL,
+  "name" varchar NOT NULL,
+  "description" text NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "posts" ADD COLUMN "category_id" bigint;
+
+ALTER TABLE "posts" ADD FOREIGN KEY ("category_id") REFERENCES "categories" ("id") ON DELETE SET NULL;
+
+CREATE INDEX ON "posts" ("category_id");
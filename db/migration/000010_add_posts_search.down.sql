@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS "posts" DROP COLUMN IF EXISTS "search";
@@ -0,0 +1,8 @@
+ALTER TABLE "posts" ADD COLUMN "search" tsvector
+  GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce("title", '')), 'A') ||
+    setweight(to_tsvector('english', coalesce("subtitle", '')), 'B') ||
+    setweight(to_tsvector('english', coalesce("content", '')), 'C')
+  ) STORED;
+
+CREATE INDEX ON "posts" USING GIN ("search");
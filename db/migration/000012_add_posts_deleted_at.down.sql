@@ -0,0 +1,3 @@
+DELETE FROM "posts" WHERE "deleted_at" IS NOT NULL;
+
+ALTER TABLE IF EXISTS "posts" DROP COLUMN IF EXISTS "deleted_at";
@@ -0,0 +1,3 @@
+ALTER TABLE "posts" ADD COLUMN "deleted_at" timestamptz;
+
+CREATE INDEX ON "posts" ("deleted_at");
@@ -0,0 +1,14 @@
+CREATE TABLE "post_revisions" (
+  "id" bigserial PRIMARY KEY,
+  "post_id" bigint NOT NULL,
+  "revision" int NOT NULL,
+  "image" varchar NOT NULL,
+  "title" varchar NOT NULL,
+  "subtitle" varchar NOT NULL,
+  "content" text NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "post_revisions" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX ON "post_revisions" ("post_id", "revision");
@@ -0,0 +1,12 @@
+ALTER TABLE "users" ADD COLUMN "token_generation" int NOT NULL DEFAULT 0;
+
+CREATE TABLE "password_resets" (
+  "id" bigserial PRIMARY KEY,
+  "user_name" varchar NOT NULL,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "is_used" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL
+);
+
+ALTER TABLE "password_resets" ADD FOREIGN KEY ("user_name") REFERENCES "users" ("user_name") ON DELETE CASCADE;
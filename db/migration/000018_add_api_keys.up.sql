@@ -0,0 +1,15 @@
+CREATE TABLE "api_keys" (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "user_name" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar NOT NULL,
+  "key_hash" varchar UNIQUE NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "expires_at" timestamptz,
+  "last_used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "api_keys" ("user_name");
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("user_name") REFERENCES "users" ("user_name") ON DELETE CASCADE;
@@ -0,0 +1,14 @@
+CREATE TABLE "user_identities" (
+  "id" bigserial PRIMARY KEY,
+  "user_name" varchar NOT NULL,
+  "provider" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "user_identities" ("provider", "subject");
+
+CREATE INDEX ON "user_identities" ("user_name");
+
+ALTER TABLE "user_identities" ADD FOREIGN KEY ("user_name") REFERENCES "users" ("user_name") ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+ALTER TABLE "comments" DROP CONSTRAINT "comments_parent_id_fkey";
+
+ALTER TABLE "comments" ADD FOREIGN KEY ("parent_id") REFERENCES "comments" ("id") ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "owner_title_key";
+
+ALTER TABLE "posts" ADD CONSTRAINT "owner_title_key" UNIQUE ("title", "owner");
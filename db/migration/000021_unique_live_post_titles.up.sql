@@ -0,0 +1,4 @@
+ALTER TABLE "posts" DROP CONSTRAINT "owner_title_key";
+
+-- Trashed posts give up their title so the owner can use it again
+CREATE UNIQUE INDEX "owner_title_key" ON "posts" ("title", "owner") WHERE "deleted_at" IS NULL;
@@ -0,0 +1,3 @@
+DELETE FROM "revoked_tokens" WHERE "user_name" NOT IN (SELECT "user_name" FROM "users");
+
+ALTER TABLE "revoked_tokens" ADD FOREIGN KEY ("user_name") REFERENCES "users" ("user_name");
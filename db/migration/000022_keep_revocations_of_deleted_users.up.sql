@@ -0,0 +1,2 @@
+-- A deleted account revokes the token it was deleted with, the revocation outlives the user until the token expires
+ALTER TABLE "revoked_tokens" DROP CONSTRAINT "revoked_tokens_user_name_fkey";
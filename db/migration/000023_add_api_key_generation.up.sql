@@ -0,0 +1,6 @@
+-- Keys remember the token generation they were created at, a password reset invalidates them like any token
+ALTER TABLE "api_keys" ADD COLUMN "token_generation" integer NOT NULL DEFAULT 0;
+
+UPDATE "api_keys" SET "token_generation" = "users"."token_generation"
+FROM "users"
+WHERE "users"."user_name" = "api_keys"."user_name";
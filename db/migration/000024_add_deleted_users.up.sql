@@ -0,0 +1,7 @@
+-- Deleted users keep their last token generation, so their tokens stay invalid
+-- and a new account with the same user name does not inherit them
+CREATE TABLE "deleted_users" (
+  "user_name" varchar PRIMARY KEY,
+  "token_generation" integer NOT NULL,
+  "deleted_at" timestamptz NOT NULL DEFAULT (now())
+);
@@ -0,0 +1,35 @@
+-- name: CreateApiKey :one
+INSERT INTO api_keys (
+  user_name,
+  name,
+  prefix,
+  key_hash,
+  scopes,
+  expires_at,
+  token_generation
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetApiKeyByHash :one
+-- The role and token generation of the owner are read along so a request
+-- authenticated with the key needs a single query
+SELECT api_keys.*, users.role, users.token_generation AS user_token_generation FROM api_keys
+JOIN users ON users.user_name = api_keys.user_name
+WHERE api_keys.key_hash = $1 LIMIT 1;
+
+-- name: ListApiKeysByUser :many
+SELECT * FROM api_keys
+WHERE user_name = $1
+ORDER BY created_at DESC;
+
+-- name: UpdateApiKeyLastUsed :exec
+-- Written at most once a minute per key
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
+
+-- name: DeleteApiKey :execrows
+DELETE FROM api_keys
+WHERE id = $1 AND user_name = $2;
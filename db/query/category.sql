@@ -0,0 +1,31 @@
+-- name: CreateCategory :one
+INSERT INTO categories (
+  slug,
+  name,
+  description
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetCategory :one
+SELECT * FROM categories
+WHERE id = $1 LIMIT 1;
+
+-- name: GetCategoryBySlug :one
+SELECT * FROM categories
+WHERE slug = $1 LIMIT 1;
+
+-- name: ListCategories :many
+SELECT * FROM categories
+ORDER BY name;
+
+-- name: UpdateCategory :one
+UPDATE categories
+SET slug = $2, name = $3, description = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteCategory :exec
+DELETE FROM categories
+WHERE id = $1;
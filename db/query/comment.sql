@@ -0,0 +1,33 @@
+-- name: CreateComment :one
+INSERT INTO comments (
+  post_id,
+  author,
+  parent_id,
+  body
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetComment :one
+SELECT * FROM comments
+WHERE id = $1 LIMIT 1;
+
+-- name: ListCommentsByPost :many
+SELECT * FROM comments
+WHERE post_id = $1
+ORDER BY created_at, id;
+
+-- name: UpdateComment :one
+UPDATE comments
+SET body = $2, updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteComment :exec
+DELETE FROM comments
+WHERE id = $1;
+
+-- name: DeleteCommentsByAuthor :exec
+DELETE FROM comments
+WHERE author = $1;
@@ -0,0 +1,10 @@
+-- name: CreateDeletedUser :one
+INSERT INTO deleted_users (
+  user_name,
+  token_generation
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (user_name) DO UPDATE
+SET token_generation = EXCLUDED.token_generation, deleted_at = now()
+RETURNING *;
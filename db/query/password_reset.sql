@@ -0,0 +1,17 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+  user_name,
+  token_hash,
+  expired_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: UsePasswordReset :one
+UPDATE password_resets
+SET is_used = TRUE
+WHERE token_hash = $1
+  AND is_used = FALSE
+  AND expired_at > now()
+RETURNING *;
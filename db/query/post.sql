@@ -4,32 +4,153 @@ INSERT INTO posts (
   image,
   title,
   subtitle,
-  content
+  content,
+  category_id
 ) VALUES (
-  $1, $2, $3, $4, $5
+  $1, $2, $3, $4, $5, $6
 )
 RETURNING *;
 
 
 -- name: GetPost :one
 SELECT * FROM posts
-WHERE id = $1 LIMIT 1;
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetDeletedPost :one
+SELECT * FROM posts
+WHERE id = $1 AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: GetPostForUpdate :one
+SELECT * FROM posts
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+FOR NO KEY UPDATE;
 
 -- name: CountPosts :one
-SELECT COUNT(*) as total_posts FROM posts;
+SELECT COUNT(*) as total_posts FROM posts
+WHERE deleted_at IS NULL
+AND (sqlc.narg(category_id)::bigint IS NULL OR category_id = sqlc.narg(category_id)::bigint)
+AND (
+  sqlc.narg(tags)::text[] IS NULL
+  OR (
+    SELECT COUNT(*) FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.name = ANY(sqlc.narg(tags)::text[])
+  ) >= CASE WHEN sqlc.arg(match_all)::bool THEN cardinality(sqlc.narg(tags)::text[]) ELSE 1 END
+)
+AND (sqlc.narg(owner)::varchar IS NULL OR owner = sqlc.narg(owner)::varchar)
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after)::timestamptz)
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before)::timestamptz);
 
 -- name: ListPosts :many
 SELECT * FROM posts
-ORDER BY id
-LIMIT $1
-OFFSET $2;
+WHERE deleted_at IS NULL
+AND (sqlc.narg(category_id)::bigint IS NULL OR category_id = sqlc.narg(category_id)::bigint)
+AND (
+  sqlc.narg(tags)::text[] IS NULL
+  OR (
+    SELECT COUNT(*) FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.name = ANY(sqlc.narg(tags)::text[])
+  ) >= CASE WHEN sqlc.arg(match_all)::bool THEN cardinality(sqlc.narg(tags)::text[]) ELSE 1 END
+)
+AND (sqlc.narg(owner)::varchar IS NULL OR owner = sqlc.narg(owner)::varchar)
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after)::timestamptz)
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before)::timestamptz)
+ORDER BY
+  CASE WHEN sqlc.arg(sort_key)::text = 'created_at' AND NOT sqlc.arg(sort_desc)::bool THEN created_at END ASC,
+  CASE WHEN sqlc.arg(sort_key)::text = 'created_at' AND sqlc.arg(sort_desc)::bool THEN created_at END DESC,
+  CASE WHEN sqlc.arg(sort_key)::text = 'title' AND NOT sqlc.arg(sort_desc)::bool THEN title END ASC,
+  CASE WHEN sqlc.arg(sort_key)::text = 'title' AND sqlc.arg(sort_desc)::bool THEN title END DESC,
+  CASE WHEN sqlc.arg(sort_key)::text = 'comments' AND NOT sqlc.arg(sort_desc)::bool THEN (
+    SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id
+  ) END ASC,
+  CASE WHEN sqlc.arg(sort_key)::text = 'comments' AND sqlc.arg(sort_desc)::bool THEN (
+    SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id
+  ) END DESC,
+  CASE WHEN sqlc.arg(sort_desc)::bool THEN id END DESC,
+  id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListPostsAfter :many
+SELECT * FROM posts
+WHERE deleted_at IS NULL
+AND (sqlc.narg(category_id)::bigint IS NULL OR category_id = sqlc.narg(category_id)::bigint)
+AND (
+  sqlc.narg(tags)::text[] IS NULL
+  OR (
+    SELECT COUNT(*) FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.name = ANY(sqlc.narg(tags)::text[])
+  ) >= CASE WHEN sqlc.arg(match_all)::bool THEN cardinality(sqlc.narg(tags)::text[]) ELSE 1 END
+)
+AND (sqlc.narg(owner)::varchar IS NULL OR owner = sqlc.narg(owner)::varchar)
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after)::timestamptz)
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before)::timestamptz)
+AND (
+  sqlc.narg(after_created_at)::timestamptz IS NULL
+  OR (created_at, id) > (sqlc.narg(after_created_at)::timestamptz, sqlc.arg(after_id)::bigint)
+)
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
 
 -- name: UpdatePost :one
 UPDATE posts
-SET content = $2
-WHERE id = $1
+SET content = $2, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: PartialUpdatePost :one
+UPDATE posts
+SET
+  image = COALESCE(sqlc.narg(image), image),
+  title = COALESCE(sqlc.narg(title), title),
+  subtitle = COALESCE(sqlc.narg(subtitle), subtitle),
+  content = COALESCE(sqlc.narg(content), content),
+  updated_at = now()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
 RETURNING *;
 
 -- name: DeletePost :exec
+UPDATE posts
+SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestorePost :one
+UPDATE posts
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: ListDeletedPostsByOwner :many
+SELECT * FROM posts
+WHERE owner = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, id
+LIMIT $2
+OFFSET $3;
+
+-- name: PurgeDeletedPosts :execrows
+DELETE FROM posts
+WHERE deleted_at IS NOT NULL AND deleted_at < sqlc.arg(deleted_before)::timestamptz;
+
+-- name: CountSearchPosts :one
+SELECT COUNT(*) as total_posts FROM posts
+WHERE deleted_at IS NULL AND search @@ websearch_to_tsquery('english', sqlc.arg(query));
+
+-- name: SearchPosts :many
+SELECT
+  id, owner, image, title, subtitle, content, created_at, updated_at, category_id,
+  ts_rank(search, websearch_to_tsquery('english', sqlc.arg(query))) AS rank,
+  ts_headline(
+    'english', content, websearch_to_tsquery('english', sqlc.arg(query)),
+    'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'
+  ) AS headline
+FROM posts
+WHERE deleted_at IS NULL AND search @@ websearch_to_tsquery('english', sqlc.arg(query))
+ORDER BY rank DESC, id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: DeletePostsByOwner :exec
 DELETE FROM posts
-WHERE id = $1;
+WHERE owner = $1;
@@ -0,0 +1,23 @@
+-- name: CreatePostRevision :one
+INSERT INTO post_revisions (
+  post_id,
+  revision,
+  image,
+  title,
+  subtitle,
+  content
+) VALUES (
+  $1, (SELECT COALESCE(MAX(revision), 0) + 1 FROM post_revisions WHERE post_id = $1)::int, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetPostRevision :one
+SELECT * FROM post_revisions
+WHERE post_id = $1 AND revision = $2 LIMIT 1;
+
+-- name: ListPostRevisions :many
+SELECT * FROM post_revisions
+WHERE post_id = $1
+ORDER BY revision DESC
+LIMIT $2
+OFFSET $3;
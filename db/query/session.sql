@@ -0,0 +1,31 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+  id,
+  user_name,
+  refresh_token,
+  user_agent,
+  client_ip,
+  is_blocked,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1 LIMIT 1;
+
+-- name: BlockSession :exec
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1;
+
+-- name: BlockSessionsByUser :exec
+UPDATE sessions
+SET is_blocked = true
+WHERE user_name = $1;
+
+-- name: DeleteSessionsByUser :exec
+DELETE FROM sessions
+WHERE user_name = $1;
@@ -0,0 +1,31 @@
+-- name: UpsertTag :one
+INSERT INTO tags (
+  name
+) VALUES (
+  $1
+)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: AddPostTag :exec
+INSERT INTO post_tags (
+  post_id,
+  tag_id
+) VALUES (
+  $1, $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListTagsByPost :many
+SELECT tags.* FROM tags
+JOIN post_tags ON post_tags.tag_id = tags.id
+WHERE post_tags.post_id = $1
+ORDER BY tags.name;
+
+-- name: ListTags :many
+SELECT tags.id, tags.name, COUNT(posts.id) AS post_count
+FROM tags
+LEFT JOIN post_tags ON post_tags.tag_id = tags.id
+LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL
+GROUP BY tags.id
+ORDER BY tags.name;
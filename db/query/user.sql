@@ -3,9 +3,11 @@ INSERT INTO users (
   user_name,
   hashed_password,
   full_name,
-  email
+  email,
+  token_generation
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4,
+  COALESCE((SELECT token_generation FROM deleted_users WHERE deleted_users.user_name = $1), 0)
 )
 RETURNING *;
 
@@ -13,8 +15,81 @@ RETURNING *;
 SELECT * FROM users
 WHERE user_name = $1 LIMIT 1;
 
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: GetUserProfile :one
+SELECT
+  user_name,
+  full_name,
+  role,
+  created_at,
+  (SELECT COUNT(*) FROM posts WHERE posts.owner = users.user_name AND posts.deleted_at IS NULL) AS post_count
+FROM users
+WHERE user_name = $1 LIMIT 1;
+
 -- name: ListUsers :many
 SELECT * FROM users
 ORDER BY id
 LIMIT $1
-OFFSET $2;
\ No newline at end of file
+OFFSET $2;
+
+-- name: ListTokenGenerations :many
+SELECT user_name, token_generation FROM users
+WHERE token_generation > 0
+UNION ALL
+SELECT user_name, token_generation FROM deleted_users
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE users.user_name = deleted_users.user_name);
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE user_name = $1
+RETURNING *;
+
+-- name: UpdateUser :one
+UPDATE users
+SET
+  full_name = COALESCE(sqlc.narg(full_name), full_name),
+  email = COALESCE(sqlc.narg(email), email),
+  is_email_verified = is_email_verified AND COALESCE(sqlc.narg(email), email) = email
+WHERE user_name = sqlc.arg(user_name)
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = TRUE
+WHERE user_name = $1 AND email = $2
+RETURNING *;
+
+-- name: ResetUserPassword :one
+UPDATE users
+SET
+  hashed_password = $2,
+  token_generation = token_generation + 1
+WHERE user_name = $1
+RETURNING *;
+
+-- name: SetUserTOTPSecret :one
+UPDATE users
+SET totp_secret = $2
+WHERE user_name = $1 AND is_totp_enabled = FALSE
+RETURNING *;
+
+-- name: EnableUserTOTP :one
+UPDATE users
+SET
+  is_totp_enabled = TRUE,
+  totp_last_step = $2
+WHERE user_name = $1 AND totp_secret <> ''
+RETURNING *;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE user_name = $1 AND totp_last_step < $2;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE user_name = $1;
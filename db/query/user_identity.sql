@@ -0,0 +1,20 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (
+  user_name,
+  provider,
+  subject,
+  email
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetUserByIdentity :one
+SELECT users.* FROM users
+JOIN user_identities ON user_identities.user_name = users.user_name
+WHERE user_identities.provider = $1 AND user_identities.subject = $2 LIMIT 1;
+
+-- name: ListUserIdentitiesByUser :many
+SELECT * FROM user_identities
+WHERE user_name = $1
+ORDER BY created_at;
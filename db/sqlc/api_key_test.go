@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomApiKey(t *testing.T, user User) ApiKey {
+
+	arg := CreateApiKeyParams{
+
+		UserName:        user.UserName,
+		Name:            util.RandomString(8),
+		Prefix:          "mef_" + util.RandomString(8),
+		KeyHash:         util.HashToken(util.RandomString(32)),
+		Scopes:          []string{util.PostsWriteScope},
+		ExpiresAt:       sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+		TokenGeneration: user.TokenGeneration,
+	}
+
+	apiKey, err := testQueries.CreateApiKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, apiKey)
+
+	require.Equal(t, arg.UserName, apiKey.UserName)
+	require.Equal(t, arg.Name, apiKey.Name)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.KeyHash, apiKey.KeyHash)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt.Time, apiKey.ExpiresAt.Time, time.Second)
+	require.Equal(t, arg.TokenGeneration, apiKey.TokenGeneration)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.NotZero(t, apiKey.ID)
+	require.NotZero(t, apiKey.CreatedAt)
+
+	return apiKey
+
+}
+
+func TestCreateApiKey(t *testing.T) {
+
+	createRandomApiKey(t, createRandomUser(t))
+
+}
+
+func TestGetApiKeyByHash(t *testing.T) {
+
+	user := createRandomUser(t)
+	apiKey1 := createRandomApiKey(t, user)
+
+	apiKey2, err := testQueries.GetApiKeyByHash(context.Background(), apiKey1.KeyHash)
+	require.NoError(t, err)
+
+	require.Equal(t, apiKey1.ID, apiKey2.ID)
+	require.Equal(t, apiKey1.UserName, apiKey2.UserName)
+	require.Equal(t, apiKey1.Scopes, apiKey2.Scopes)
+	require.Equal(t, user.Role, apiKey2.Role)
+	require.Equal(t, apiKey1.TokenGeneration, apiKey2.TokenGeneration)
+	require.Equal(t, user.TokenGeneration, apiKey2.UserTokenGeneration)
+
+	_, err = testQueries.GetApiKeyByHash(context.Background(), util.HashToken(util.RandomString(32)))
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestListApiKeysByUser(t *testing.T) {
+
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomApiKey(t, user)
+	}
+
+	apiKeys, err := testQueries.ListApiKeysByUser(context.Background(), user.UserName)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+
+	for _, apiKey := range apiKeys {
+		require.Equal(t, user.UserName, apiKey.UserName)
+	}
+
+}
+
+func TestUpdateApiKeyLastUsed(t *testing.T) {
+
+	apiKey1 := createRandomApiKey(t, createRandomUser(t))
+
+	err := testQueries.UpdateApiKeyLastUsed(context.Background(), apiKey1.ID)
+	require.NoError(t, err)
+
+	apiKey2, err := testQueries.GetApiKeyByHash(context.Background(), apiKey1.KeyHash)
+	require.NoError(t, err)
+	require.True(t, apiKey2.LastUsedAt.Valid)
+	require.WithinDuration(t, time.Now(), apiKey2.LastUsedAt.Time, time.Second)
+
+}
+
+func TestDeleteApiKey(t *testing.T) {
+
+	user := createRandomUser(t)
+	apiKey := createRandomApiKey(t, user)
+
+	// Keys of other users are left alone
+	deleted, err := testQueries.DeleteApiKey(context.Background(), DeleteApiKeyParams{
+		ID:       apiKey.ID,
+		UserName: createRandomUser(t).UserName,
+	})
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	deleted, err = testQueries.DeleteApiKey(context.Background(), DeleteApiKeyParams{
+		ID:       apiKey.ID,
+		UserName: user.UserName,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = testQueries.GetApiKeyByHash(context.Background(), apiKey.KeyHash)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomCategory(t *testing.T) Category {
+
+	arg := CreateCategoryParams{
+
+		Slug:        util.RandomString(12),
+		Name:        util.RandomTitle(),
+		Description: util.RandomSubtitle(),
+	}
+
+	category, err := testQueries.CreateCategory(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, category)
+
+	require.Equal(t, arg.Slug, category.Slug)
+	require.Equal(t, arg.Name, category.Name)
+	require.Equal(t, arg.Description, category.Description)
+
+	require.NotZero(t, category.ID)
+	require.NotZero(t, category.CreatedAt)
+
+	return category
+
+}
+
+func TestCreateCategory(t *testing.T) {
+
+	createRandomCategory(t)
+
+}
+
+func TestGetCategory(t *testing.T) {
+
+	category1 := createRandomCategory(t)
+	category2, err := testQueries.GetCategory(context.Background(), category1.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, category2)
+
+	require.Equal(t, category1.ID, category2.ID)
+	require.Equal(t, category1.Slug, category2.Slug)
+	require.Equal(t, category1.Name, category2.Name)
+	require.Equal(t, category1.Description, category2.Description)
+	require.WithinDuration(t, category1.CreatedAt, category2.CreatedAt, time.Second)
+
+}
+
+func TestGetCategoryBySlug(t *testing.T) {
+
+	category1 := createRandomCategory(t)
+	category2, err := testQueries.GetCategoryBySlug(context.Background(), category1.Slug)
+	require.NoError(t, err)
+	require.Equal(t, category1.ID, category2.ID)
+
+}
+
+func TestUpdateCategory(t *testing.T) {
+
+	category1 := createRandomCategory(t)
+
+	arg := UpdateCategoryParams{
+
+		ID:          category1.ID,
+		Slug:        util.RandomString(12),
+		Name:        util.RandomTitle(),
+		Description: util.RandomSubtitle(),
+	}
+
+	category2, err := testQueries.UpdateCategory(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, category2)
+
+	require.Equal(t, category1.ID, category2.ID)
+	require.Equal(t, arg.Slug, category2.Slug)
+	require.Equal(t, arg.Name, category2.Name)
+	require.Equal(t, arg.Description, category2.Description)
+
+}
+
+func TestDeleteCategory(t *testing.T) {
+
+	category1 := createRandomCategory(t)
+	err := testQueries.DeleteCategory(context.Background(), category1.ID)
+	require.NoError(t, err)
+
+	category2, err := testQueries.GetCategory(context.Background(), category1.ID)
+	require.Error(t, err)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, category2)
+
+}
+
+func TestListCategories(t *testing.T) {
+
+	for i := 0; i < 3; i++ {
+
+		createRandomCategory(t)
+
+	}
+
+	categories, err := testQueries.ListCategories(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(categories), 3)
+
+}
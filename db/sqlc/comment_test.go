@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomComment(t *testing.T, post Post, parentID sql.NullInt64) Comment {
+	user := createRandomUser(t)
+
+	arg := CreateCommentParams{
+
+		PostID:   post.ID,
+		Author:   user.UserName,
+		ParentID: parentID,
+		Body:     util.RandomContent(),
+	}
+
+	comment, err := testQueries.CreateComment(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, comment)
+
+	require.Equal(t, arg.PostID, comment.PostID)
+	require.Equal(t, arg.Author, comment.Author)
+	require.Equal(t, arg.ParentID, comment.ParentID)
+	require.Equal(t, arg.Body, comment.Body)
+
+	require.NotZero(t, comment.ID)
+	require.NotZero(t, comment.CreatedAt)
+
+	return comment
+
+}
+
+func TestCreateComment(t *testing.T) {
+
+	post := createRandomPost(t)
+	createRandomComment(t, post, sql.NullInt64{})
+
+}
+
+func TestGetComment(t *testing.T) {
+
+	post := createRandomPost(t)
+	comment1 := createRandomComment(t, post, sql.NullInt64{})
+	comment2, err := testQueries.GetComment(context.Background(), comment1.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, comment2)
+
+	require.Equal(t, comment1.ID, comment2.ID)
+	require.Equal(t, comment1.PostID, comment2.PostID)
+	require.Equal(t, comment1.Author, comment2.Author)
+	require.Equal(t, comment1.Body, comment2.Body)
+	require.WithinDuration(t, comment1.CreatedAt, comment2.CreatedAt, time.Second)
+
+}
+
+func TestListCommentsByPost(t *testing.T) {
+
+	post := createRandomPost(t)
+	root := createRandomComment(t, post, sql.NullInt64{})
+	reply := createRandomComment(t, post, sql.NullInt64{Int64: root.ID, Valid: true})
+
+	// Comments of other posts must not be listed
+	createRandomComment(t, createRandomPost(t), sql.NullInt64{})
+
+	comments, err := testQueries.ListCommentsByPost(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+
+	require.Equal(t, root.ID, comments[0].ID)
+	require.Equal(t, reply.ID, comments[1].ID)
+	require.Equal(t, root.ID, comments[1].ParentID.Int64)
+
+}
+
+func TestUpdateComment(t *testing.T) {
+
+	post := createRandomPost(t)
+	comment1 := createRandomComment(t, post, sql.NullInt64{})
+
+	args := UpdateCommentParams{
+
+		ID:   comment1.ID,
+		Body: util.RandomContent(),
+	}
+
+	comment2, err := testQueries.UpdateComment(context.Background(), args)
+	require.NoError(t, err)
+	require.NotEmpty(t, comment2)
+
+	require.Equal(t, comment1.ID, comment2.ID)
+	require.Equal(t, args.Body, comment2.Body)
+	require.True(t, comment2.UpdatedAt.After(comment1.UpdatedAt))
+
+}
+
+func TestDeleteComment(t *testing.T) {
+
+	post := createRandomPost(t)
+	root := createRandomComment(t, post, sql.NullInt64{})
+	reply := createRandomComment(t, post, sql.NullInt64{Int64: root.ID, Valid: true})
+
+	err := testQueries.DeleteComment(context.Background(), root.ID)
+	require.NoError(t, err)
+
+	comment, err := testQueries.GetComment(context.Background(), root.ID)
+	require.Error(t, err)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, comment)
+
+	// The reply of another user stays, it just has no parent anymore
+	comment, err = testQueries.GetComment(context.Background(), reply.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, root.Author, comment.Author)
+	require.Equal(t, reply.Body, comment.Body)
+	require.False(t, comment.ParentID.Valid)
+
+}
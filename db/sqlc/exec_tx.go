@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//Number of times a transaction is attempted before a serialization failure is returned
+const maxTxAttempts = 3
+
+//Base delay between attempts, multiplied by the attempt number
+const txRetryBackoff = 20 * time.Millisecond
+
+//execTx executes fn within a DB transaction at the given isolation level.
+//The whole transaction is retried when Postgres cannot serialize it.
+func (store *SQLStore) execTx(ctx context.Context, isolation sql.IsolationLevel, fn func(*Queries) error) error {
+
+	var err error
+
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+
+		err = store.runTx(ctx, isolation, fn)
+		if !isSerializationFailure(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * txRetryBackoff):
+		}
+
+	}
+
+	return err
+
+}
+
+func (store *SQLStore) runTx(ctx context.Context, isolation sql.IsolationLevel, fn func(*Queries) error) error {
+
+	tx, err := store.db.BeginTx(ctx, &sql.TxOptions{Isolation: isolation})
+	if err != nil {
+		return err
+	}
+
+	err = fn(New(tx))
+	if err != nil {
+
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
+		}
+		return err
+
+	}
+
+	return tx.Commit()
+
+}
+
+//Serialization failures (40001) and deadlocks (40P01) succeed when the transaction is run again
+func isSerializationFailure(err error) bool {
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected":
+		return true
+	}
+
+	return false
+
+}
@@ -5,14 +5,76 @@
 package db
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID              uuid.UUID    `json:"id"`
+	UserName        string       `json:"user_name"`
+	Name            string       `json:"name"`
+	Prefix          string       `json:"prefix"`
+	KeyHash         string       `json:"key_hash"`
+	Scopes          []string     `json:"scopes"`
+	ExpiresAt       sql.NullTime `json:"expires_at"`
+	LastUsedAt      sql.NullTime `json:"last_used_at"`
+	CreatedAt       time.Time    `json:"created_at"`
+	TokenGeneration int32        `json:"token_generation"`
+}
+
+type Category struct {
+	ID          int64     `json:"id"`
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Comment struct {
+	ID        int64         `json:"id"`
+	PostID    int64         `json:"post_id"`
+	Author    string        `json:"author"`
+	ParentID  sql.NullInt64 `json:"parent_id"`
+	Body      string        `json:"body"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type DeletedUser struct {
+	UserName        string    `json:"user_name"`
+	TokenGeneration int32     `json:"token_generation"`
+	DeletedAt       time.Time `json:"deleted_at"`
+}
+
+type PasswordReset struct {
+	ID        int64     `json:"id"`
+	UserName  string    `json:"user_name"`
+	TokenHash string    `json:"token_hash"`
+	IsUsed    bool      `json:"is_used"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
 type Post struct {
+	ID         int64      `json:"id"`
+	Owner      string     `json:"owner"`
+	Image      string     `json:"image"`
+	Title      string     `json:"title"`
+	Subtitle   string     `json:"subtitle"`
+	Content    string     `json:"content"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	CategoryID *int64     `json:"category_id"`
+	Search     string     `json:"-"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+}
+
+type PostRevision struct {
 	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
+	PostID    int64     `json:"post_id"`
+	Revision  int32     `json:"revision"`
 	Image     string    `json:"image"`
 	Title     string    `json:"title"`
 	Subtitle  string    `json:"subtitle"`
@@ -20,11 +82,80 @@ type Post struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type PostTag struct {
+	PostID int64 `json:"post_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+type RateLimitBucket struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	Allowed   bool      `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	ID        int64        `json:"id"`
+	UserName  string       `json:"user_name"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	UserName  string    `json:"user_name"`
+	ExpiredAt time.Time `json:"expired_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	UserName     string    `json:"user_name"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	UserName       string    `json:"user_name"`
-	HashedPassword string    `json:"hashed_password"`
-	FullName       string    `json:"full_name"`
-	Email          string    `json:"email"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID              uuid.UUID `json:"id"`
+	UserName        string    `json:"user_name"`
+	HashedPassword  string    `json:"hashed_password"`
+	FullName        string    `json:"full_name"`
+	Email           string    `json:"email"`
+	CreatedAt       time.Time `json:"created_at"`
+	Role            string    `json:"role"`
+	IsEmailVerified bool      `json:"is_email_verified"`
+	TokenGeneration int32     `json:"token_generation"`
+	TotpSecret      string    `json:"totp_secret"`
+	IsTotpEnabled   bool      `json:"is_totp_enabled"`
+	TotpLastStep    int64     `json:"totp_last_step"`
+}
+
+type UserIdentity struct {
+	ID        int64     `json:"id"`
+	UserName  string    `json:"user_name"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type VerifyEmail struct {
+	ID         int64     `json:"id"`
+	UserName   string    `json:"user_name"`
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
 }
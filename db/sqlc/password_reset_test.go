@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordReset(t *testing.T, user User, expiredAt time.Time) PasswordReset {
+	arg := CreatePasswordResetParams{
+
+		UserName:  user.UserName,
+		TokenHash: util.HashToken(util.RandomString(32)),
+		ExpiredAt: expiredAt,
+	}
+
+	reset, err := testQueries.CreatePasswordReset(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.UserName, reset.UserName)
+	require.Equal(t, arg.TokenHash, reset.TokenHash)
+	require.False(t, reset.IsUsed)
+	require.WithinDuration(t, arg.ExpiredAt, reset.ExpiredAt, time.Second)
+
+	require.NotZero(t, reset.ID)
+	require.NotZero(t, reset.CreatedAt)
+
+	return reset
+
+}
+
+func TestCreatePasswordReset(t *testing.T) {
+
+	createRandomPasswordReset(t, createRandomUser(t), time.Now().Add(time.Hour))
+
+}
+
+func TestUsePasswordReset(t *testing.T) {
+
+	reset := createRandomPasswordReset(t, createRandomUser(t), time.Now().Add(time.Hour))
+
+	used, err := testQueries.UsePasswordReset(context.Background(), reset.TokenHash)
+	require.NoError(t, err)
+	require.True(t, used.IsUsed)
+
+	// A token only works once
+	_, err = testQueries.UsePasswordReset(context.Background(), reset.TokenHash)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestUseExpiredPasswordReset(t *testing.T) {
+
+	reset := createRandomPasswordReset(t, createRandomUser(t), time.Now().Add(-time.Minute))
+
+	_, err := testQueries.UsePasswordReset(context.Background(), reset.TokenHash)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
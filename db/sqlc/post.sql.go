@@ -7,14 +7,59 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 const countPosts = `-- name: CountPosts :one
 SELECT COUNT(*) as total_posts FROM posts
+WHERE deleted_at IS NULL
+AND ($1::bigint IS NULL OR category_id = $1::bigint)
+AND (
+  $2::text[] IS NULL
+  OR (
+    SELECT COUNT(*) FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.name = ANY($2::text[])
+  ) >= CASE WHEN $3::bool THEN cardinality($2::text[]) ELSE 1 END
+)
+AND ($4::varchar IS NULL OR owner = $4::varchar)
+AND ($5::timestamptz IS NULL OR created_at >= $5::timestamptz)
+AND ($6::timestamptz IS NULL OR created_at < $6::timestamptz)
+`
+
+type CountPostsParams struct {
+	CategoryID    sql.NullInt64  `json:"category_id"`
+	Tags          []string       `json:"tags"`
+	MatchAll      bool           `json:"match_all"`
+	Owner         sql.NullString `json:"owner"`
+	CreatedAfter  sql.NullTime   `json:"created_after"`
+	CreatedBefore sql.NullTime   `json:"created_before"`
+}
+
+func (q *Queries) CountPosts(ctx context.Context, arg CountPostsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPosts,
+		arg.CategoryID,
+		pq.Array(arg.Tags),
+		arg.MatchAll,
+		arg.Owner,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+	)
+	var total_posts int64
+	err := row.Scan(&total_posts)
+	return total_posts, err
+}
+
+const countSearchPosts = `-- name: CountSearchPosts :one
+SELECT COUNT(*) as total_posts FROM posts
+WHERE deleted_at IS NULL AND search @@ websearch_to_tsquery('english', $1)
 `
 
-func (q *Queries) CountPosts(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countPosts)
+func (q *Queries) CountSearchPosts(ctx context.Context, query string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSearchPosts, query)
 	var total_posts int64
 	err := row.Scan(&total_posts)
 	return total_posts, err
@@ -26,19 +71,21 @@ INSERT INTO posts (
   image,
   title,
   subtitle,
-  content
+  content,
+  category_id
 ) VALUES (
-  $1, $2, $3, $4, $5
+  $1, $2, $3, $4, $5, $6
 )
-RETURNING id, owner, image, title, subtitle, content, created_at
+RETURNING id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at
 `
 
 type CreatePostParams struct {
-	Owner    string `json:"owner"`
-	Image    string `json:"image"`
-	Title    string `json:"title"`
-	Subtitle string `json:"subtitle"`
-	Content  string `json:"content"`
+	Owner      string `json:"owner"`
+	Image      string `json:"image"`
+	Title      string `json:"title"`
+	Subtitle   string `json:"subtitle"`
+	Content    string `json:"content"`
+	CategoryID *int64 `json:"category_id"`
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -48,6 +95,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Title,
 		arg.Subtitle,
 		arg.Content,
+		arg.CategoryID,
 	)
 	var i Post
 	err := row.Scan(
@@ -58,13 +106,18 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Subtitle,
 		&i.Content,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CategoryID,
+		&i.Search,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const deletePost = `-- name: DeletePost :exec
-DELETE FROM posts
-WHERE id = $1
+UPDATE posts
+SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) DeletePost(ctx context.Context, id int64) error {
@@ -72,9 +125,43 @@ func (q *Queries) DeletePost(ctx context.Context, id int64) error {
 	return err
 }
 
+const deletePostsByOwner = `-- name: DeletePostsByOwner :exec
+DELETE FROM posts
+WHERE owner = $1
+`
+
+func (q *Queries) DeletePostsByOwner(ctx context.Context, owner string) error {
+	_, err := q.db.ExecContext(ctx, deletePostsByOwner, owner)
+	return err
+}
+
+const getDeletedPost = `-- name: GetDeletedPost :one
+SELECT id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at FROM posts
+WHERE id = $1 AND deleted_at IS NOT NULL LIMIT 1
+`
+
+func (q *Queries) GetDeletedPost(ctx context.Context, id int64) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Image,
+		&i.Title,
+		&i.Subtitle,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CategoryID,
+		&i.Search,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const getPost = `-- name: GetPost :one
-SELECT id, owner, image, title, subtitle, content, created_at FROM posts
-WHERE id = $1 LIMIT 1
+SELECT id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at FROM posts
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 func (q *Queries) GetPost(ctx context.Context, id int64) (Post, error) {
@@ -88,24 +175,226 @@ func (q *Queries) GetPost(ctx context.Context, id int64) (Post, error) {
 		&i.Subtitle,
 		&i.Content,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CategoryID,
+		&i.Search,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getPostForUpdate = `-- name: GetPostForUpdate :one
+SELECT id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at FROM posts
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetPostForUpdate(ctx context.Context, id int64) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUpdate, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Image,
+		&i.Title,
+		&i.Subtitle,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CategoryID,
+		&i.Search,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
+const listDeletedPostsByOwner = `-- name: ListDeletedPostsByOwner :many
+SELECT id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at FROM posts
+WHERE owner = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, id
+LIMIT $2
+OFFSET $3
+`
+
+type ListDeletedPostsByOwnerParams struct {
+	Owner  string `json:"owner"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListDeletedPostsByOwner(ctx context.Context, arg ListDeletedPostsByOwnerParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listDeletedPostsByOwner, arg.Owner, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Image,
+			&i.Title,
+			&i.Subtitle,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CategoryID,
+			&i.Search,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPosts = `-- name: ListPosts :many
-SELECT id, owner, image, title, subtitle, content, created_at FROM posts
-ORDER BY id
-LIMIT $1
-OFFSET $2
+SELECT id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at FROM posts
+WHERE deleted_at IS NULL
+AND ($1::bigint IS NULL OR category_id = $1::bigint)
+AND (
+  $2::text[] IS NULL
+  OR (
+    SELECT COUNT(*) FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.name = ANY($2::text[])
+  ) >= CASE WHEN $3::bool THEN cardinality($2::text[]) ELSE 1 END
+)
+AND ($4::varchar IS NULL OR owner = $4::varchar)
+AND ($5::timestamptz IS NULL OR created_at >= $5::timestamptz)
+AND ($6::timestamptz IS NULL OR created_at < $6::timestamptz)
+ORDER BY
+  CASE WHEN $7::text = 'created_at' AND NOT $8::bool THEN created_at END ASC,
+  CASE WHEN $7::text = 'created_at' AND $8::bool THEN created_at END DESC,
+  CASE WHEN $7::text = 'title' AND NOT $8::bool THEN title END ASC,
+  CASE WHEN $7::text = 'title' AND $8::bool THEN title END DESC,
+  CASE WHEN $7::text = 'comments' AND NOT $8::bool THEN (
+    SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id
+  ) END ASC,
+  CASE WHEN $7::text = 'comments' AND $8::bool THEN (
+    SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id
+  ) END DESC,
+  CASE WHEN $8::bool THEN id END DESC,
+  id
+LIMIT $9
+OFFSET $10
 `
 
 type ListPostsParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	CategoryID    sql.NullInt64  `json:"category_id"`
+	Tags          []string       `json:"tags"`
+	MatchAll      bool           `json:"match_all"`
+	Owner         sql.NullString `json:"owner"`
+	CreatedAfter  sql.NullTime   `json:"created_after"`
+	CreatedBefore sql.NullTime   `json:"created_before"`
+	SortKey       string         `json:"sort_key"`
+	SortDesc      bool           `json:"sort_desc"`
+	Limit         int32          `json:"limit"`
+	Offset        int32          `json:"offset"`
 }
 
 func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, listPosts, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listPosts,
+		arg.CategoryID,
+		pq.Array(arg.Tags),
+		arg.MatchAll,
+		arg.Owner,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.SortKey,
+		arg.SortDesc,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Image,
+			&i.Title,
+			&i.Subtitle,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CategoryID,
+			&i.Search,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostsAfter = `-- name: ListPostsAfter :many
+SELECT id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at FROM posts
+WHERE deleted_at IS NULL
+AND ($1::bigint IS NULL OR category_id = $1::bigint)
+AND (
+  $2::text[] IS NULL
+  OR (
+    SELECT COUNT(*) FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.name = ANY($2::text[])
+  ) >= CASE WHEN $3::bool THEN cardinality($2::text[]) ELSE 1 END
+)
+AND ($4::varchar IS NULL OR owner = $4::varchar)
+AND ($5::timestamptz IS NULL OR created_at >= $5::timestamptz)
+AND ($6::timestamptz IS NULL OR created_at < $6::timestamptz)
+AND (
+  $7::timestamptz IS NULL
+  OR (created_at, id) > ($7::timestamptz, $8::bigint)
+)
+ORDER BY created_at, id
+LIMIT $9
+`
+
+type ListPostsAfterParams struct {
+	CategoryID     sql.NullInt64  `json:"category_id"`
+	Tags           []string       `json:"tags"`
+	MatchAll       bool           `json:"match_all"`
+	Owner          sql.NullString `json:"owner"`
+	CreatedAfter   sql.NullTime   `json:"created_after"`
+	CreatedBefore  sql.NullTime   `json:"created_before"`
+	AfterCreatedAt sql.NullTime   `json:"after_created_at"`
+	AfterID        int64          `json:"after_id"`
+	Limit          int32          `json:"limit"`
+}
+
+func (q *Queries) ListPostsAfter(ctx context.Context, arg ListPostsAfterParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listPostsAfter,
+		arg.CategoryID,
+		pq.Array(arg.Tags),
+		arg.MatchAll,
+		arg.Owner,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +410,164 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]Post, e
 			&i.Subtitle,
 			&i.Content,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CategoryID,
+			&i.Search,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const partialUpdatePost = `-- name: PartialUpdatePost :one
+UPDATE posts
+SET
+  image = COALESCE($1, image),
+  title = COALESCE($2, title),
+  subtitle = COALESCE($3, subtitle),
+  content = COALESCE($4, content),
+  updated_at = now()
+WHERE id = $5 AND deleted_at IS NULL
+RETURNING id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at
+`
+
+type PartialUpdatePostParams struct {
+	Image    sql.NullString `json:"image"`
+	Title    sql.NullString `json:"title"`
+	Subtitle sql.NullString `json:"subtitle"`
+	Content  sql.NullString `json:"content"`
+	ID       int64          `json:"id"`
+}
+
+func (q *Queries) PartialUpdatePost(ctx context.Context, arg PartialUpdatePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, partialUpdatePost,
+		arg.Image,
+		arg.Title,
+		arg.Subtitle,
+		arg.Content,
+		arg.ID,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Image,
+		&i.Title,
+		&i.Subtitle,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CategoryID,
+		&i.Search,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const purgeDeletedPosts = `-- name: PurgeDeletedPosts :execrows
+DELETE FROM posts
+WHERE deleted_at IS NOT NULL AND deleted_at < $1::timestamptz
+`
+
+func (q *Queries) PurgeDeletedPosts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedPosts, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restorePost = `-- name: RestorePost :one
+UPDATE posts
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at
+`
+
+func (q *Queries) RestorePost(ctx context.Context, id int64) (Post, error) {
+	row := q.db.QueryRowContext(ctx, restorePost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Image,
+		&i.Title,
+		&i.Subtitle,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CategoryID,
+		&i.Search,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+  id, owner, image, title, subtitle, content, created_at, updated_at, category_id,
+  ts_rank(search, websearch_to_tsquery('english', $1)) AS rank,
+  ts_headline(
+    'english', content, websearch_to_tsquery('english', $1),
+    'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'
+  ) AS headline
+FROM posts
+WHERE deleted_at IS NULL AND search @@ websearch_to_tsquery('english', $1)
+ORDER BY rank DESC, id
+LIMIT $2
+OFFSET $3
+`
+
+type SearchPostsParams struct {
+	Query  string `json:"query"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+type SearchPostsRow struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	Image      string    `json:"image"`
+	Title      string    `json:"title"`
+	Subtitle   string    `json:"subtitle"`
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	CategoryID *int64    `json:"category_id"`
+	Rank       float32   `json:"rank"`
+	Headline   string    `json:"headline"`
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts, arg.Query, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchPostsRow{}
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Image,
+			&i.Title,
+			&i.Subtitle,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CategoryID,
+			&i.Rank,
+			&i.Headline,
 		); err != nil {
 			return nil, err
 		}
@@ -137,9 +584,9 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]Post, e
 
 const updatePost = `-- name: UpdatePost :one
 UPDATE posts
-SET content = $2
-WHERE id = $1
-RETURNING id, owner, image, title, subtitle, content, created_at
+SET content = $2, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, owner, image, title, subtitle, content, created_at, updated_at, category_id, search, deleted_at
 `
 
 type UpdatePostParams struct {
@@ -158,6 +605,10 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		&i.Subtitle,
 		&i.Content,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CategoryID,
+		&i.Search,
+		&i.DeletedAt,
 	)
 	return i, err
 }
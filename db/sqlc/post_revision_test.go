@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPostRevision(t *testing.T, post Post) PostRevision {
+	arg := CreatePostRevisionParams{
+
+		PostID:   post.ID,
+		Image:    post.Image,
+		Title:    post.Title,
+		Subtitle: post.Subtitle,
+		Content:  util.RandomContent(),
+	}
+
+	revision, err := testQueries.CreatePostRevision(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, revision)
+
+	require.Equal(t, arg.PostID, revision.PostID)
+	require.Equal(t, arg.Title, revision.Title)
+	require.Equal(t, arg.Content, revision.Content)
+
+	require.NotZero(t, revision.ID)
+	require.NotZero(t, revision.Revision)
+	require.NotZero(t, revision.CreatedAt)
+
+	return revision
+
+}
+
+func TestCreatePostRevision(t *testing.T) {
+
+	post := createRandomPost(t)
+
+	revision1 := createRandomPostRevision(t, post)
+	revision2 := createRandomPostRevision(t, post)
+
+	require.Equal(t, int32(1), revision1.Revision)
+	require.Equal(t, int32(2), revision2.Revision)
+
+}
+
+func TestGetPostRevision(t *testing.T) {
+
+	post := createRandomPost(t)
+	revision1 := createRandomPostRevision(t, post)
+
+	revision2, err := testQueries.GetPostRevision(context.Background(), GetPostRevisionParams{
+		PostID:   post.ID,
+		Revision: revision1.Revision,
+	})
+	require.NoError(t, err)
+	require.Equal(t, revision1, revision2)
+
+	_, err = testQueries.GetPostRevision(context.Background(), GetPostRevisionParams{
+		PostID:   post.ID,
+		Revision: revision1.Revision + 1,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestListPostRevisions(t *testing.T) {
+
+	post := createRandomPost(t)
+
+	for i := 0; i < 6; i++ {
+
+		createRandomPostRevision(t, post)
+
+	}
+
+	args := ListPostRevisionsParams{
+
+		PostID: post.ID,
+		Limit:  5,
+		Offset: 0,
+	}
+
+	revisions, err := testQueries.ListPostRevisions(context.Background(), args)
+	require.NoError(t, err)
+	require.Len(t, revisions, 5)
+
+	// Newest revision first
+	require.Equal(t, int32(6), revisions[0].Revision)
+	require.Equal(t, int32(2), revisions[4].Revision)
+
+}
@@ -3,10 +3,12 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/CM-IV/mef-api/util"
+	"github.com/lib/pq"
 
 	"github.com/stretchr/testify/require"
 )
@@ -83,6 +85,33 @@ func TestUpdatePost(t *testing.T) {
 	require.Equal(t, post1.Subtitle, post2.Subtitle)
 	require.Equal(t, args.Content, post2.Content)
 	require.WithinDuration(t, post1.CreatedAt, post2.CreatedAt, time.Second)
+	require.True(t, post2.UpdatedAt.After(post1.UpdatedAt))
+
+}
+
+func TestPartialUpdatePost(t *testing.T) {
+
+	post1 := createRandomPost(t)
+
+	args := PartialUpdatePostParams{
+
+		ID:       post1.ID,
+		Title:    sql.NullString{String: util.RandomTitle(), Valid: true},
+		Subtitle: sql.NullString{String: util.RandomSubtitle(), Valid: true},
+	}
+
+	post2, err := testQueries.PartialUpdatePost(context.Background(), args)
+	require.NoError(t, err)
+	require.NotEmpty(t, post2)
+
+	require.Equal(t, post1.ID, post2.ID)
+	require.Equal(t, post1.Owner, post2.Owner)
+	require.Equal(t, post1.Image, post2.Image)
+	require.Equal(t, args.Title.String, post2.Title)
+	require.Equal(t, args.Subtitle.String, post2.Subtitle)
+	require.Equal(t, post1.Content, post2.Content)
+	require.WithinDuration(t, post1.CreatedAt, post2.CreatedAt, time.Second)
+	require.True(t, post2.UpdatedAt.After(post1.UpdatedAt))
 
 }
 
@@ -99,6 +128,97 @@ func TestDeletePost(t *testing.T) {
 
 }
 
+func TestRestorePost(t *testing.T) {
+
+	post1 := createRandomPost(t)
+	err := testQueries.DeletePost(context.Background(), post1.ID)
+	require.NoError(t, err)
+
+	deleted, err := testQueries.GetDeletedPost(context.Background(), post1.ID)
+	require.NoError(t, err)
+	require.NotNil(t, deleted.DeletedAt)
+
+	post2, err := testQueries.RestorePost(context.Background(), post1.ID)
+	require.NoError(t, err)
+	require.Equal(t, post1.ID, post2.ID)
+	require.Nil(t, post2.DeletedAt)
+
+	_, err = testQueries.GetPost(context.Background(), post1.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetDeletedPost(context.Background(), post1.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestRestorePostTitleTaken(t *testing.T) {
+
+	post1 := createRandomPost(t)
+	err := testQueries.DeletePost(context.Background(), post1.ID)
+	require.NoError(t, err)
+
+	// The title of a trashed post can be used again by its owner
+	post2, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		Owner:    post1.Owner,
+		Image:    util.RandomImage(),
+		Title:    post1.Title,
+		Subtitle: util.RandomSubtitle(),
+		Content:  util.RandomContent(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, post1.Title, post2.Title)
+
+	// Then the trashed post cannot come back with the same title
+	_, err = testQueries.RestorePost(context.Background(), post1.ID)
+	require.Error(t, err)
+
+	var pqErr *pq.Error
+	require.True(t, errors.As(err, &pqErr))
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+
+}
+
+func TestListDeletedPostsByOwner(t *testing.T) {
+
+	post := createRandomPost(t)
+	err := testQueries.DeletePost(context.Background(), post.ID)
+	require.NoError(t, err)
+
+	arg := ListDeletedPostsByOwnerParams{
+
+		Owner:  post.Owner,
+		Limit:  5,
+		Offset: 0,
+	}
+
+	posts, err := testQueries.ListDeletedPostsByOwner(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	require.Equal(t, post.ID, posts[0].ID)
+
+	count, err := testQueries.CountPosts(context.Background(), CountPostsParams{
+		Owner: sql.NullString{String: post.Owner, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+}
+
+func TestPurgeDeletedPosts(t *testing.T) {
+
+	post := createRandomPost(t)
+	err := testQueries.DeletePost(context.Background(), post.ID)
+	require.NoError(t, err)
+
+	purged, err := testQueries.PurgeDeletedPosts(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, purged, int64(1))
+
+	_, err = testQueries.GetDeletedPost(context.Background(), post.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
 func TestListPosts(t *testing.T) {
 
 	for i := 0; i < 10; i++ {
@@ -124,3 +244,192 @@ func TestListPosts(t *testing.T) {
 	}
 
 }
+
+func TestListPostsByCategory(t *testing.T) {
+
+	category := createRandomCategory(t)
+	user := createRandomUser(t)
+
+	for i := 0; i < 3; i++ {
+
+		_, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+			Owner:      user.UserName,
+			Image:      util.RandomImage(),
+			Title:      util.RandomTitle(),
+			Subtitle:   util.RandomSubtitle(),
+			Content:    util.RandomContent(),
+			CategoryID: &category.ID,
+		})
+		require.NoError(t, err)
+
+	}
+
+	categoryID := sql.NullInt64{Int64: category.ID, Valid: true}
+
+	count, err := testQueries.CountPosts(context.Background(), CountPostsParams{CategoryID: categoryID})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	posts, err := testQueries.ListPosts(context.Background(), ListPostsParams{
+		CategoryID: categoryID,
+		Limit:      5,
+		Offset:     0,
+	})
+	require.NoError(t, err)
+	require.Len(t, posts, 3)
+
+	for _, post := range posts {
+
+		require.NotNil(t, post.CategoryID)
+		require.Equal(t, category.ID, *post.CategoryID)
+
+	}
+
+}
+
+func TestSearchPosts(t *testing.T) {
+
+	user := createRandomUser(t)
+	word := util.RandomString(12)
+
+	post, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		Owner:    user.UserName,
+		Image:    util.RandomImage(),
+		Title:    util.RandomTitle(),
+		Subtitle: util.RandomSubtitle(),
+		Content:  "a post about " + word + " and nothing else",
+	})
+	require.NoError(t, err)
+
+	count, err := testQueries.CountSearchPosts(context.Background(), word)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	rows, err := testQueries.SearchPosts(context.Background(), SearchPostsParams{
+		Query:  word,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	require.Equal(t, post.ID, rows[0].ID)
+	require.Greater(t, rows[0].Rank, float32(0))
+	require.Contains(t, rows[0].Headline, "<mark>"+word+"</mark>")
+
+}
+
+func TestListPostsAfter(t *testing.T) {
+
+	category := createRandomCategory(t)
+	user := createRandomUser(t)
+
+	for i := 0; i < 7; i++ {
+
+		_, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+			Owner:      user.UserName,
+			Image:      util.RandomImage(),
+			Title:      util.RandomTitle(),
+			Subtitle:   util.RandomSubtitle(),
+			Content:    util.RandomContent(),
+			CategoryID: &category.ID,
+		})
+		require.NoError(t, err)
+
+	}
+
+	categoryID := sql.NullInt64{Int64: category.ID, Valid: true}
+
+	page1, err := testQueries.ListPostsAfter(context.Background(), ListPostsAfterParams{
+		CategoryID: categoryID,
+		Limit:      5,
+	})
+	require.NoError(t, err)
+	require.Len(t, page1, 5)
+
+	last := page1[len(page1)-1]
+
+	page2, err := testQueries.ListPostsAfter(context.Background(), ListPostsAfterParams{
+		CategoryID:     categoryID,
+		AfterCreatedAt: sql.NullTime{Time: last.CreatedAt, Valid: true},
+		AfterID:        last.ID,
+		Limit:          5,
+	})
+	require.NoError(t, err)
+	require.Len(t, page2, 2)
+
+	for _, post := range page2 {
+
+		require.False(t, post.CreatedAt.Before(last.CreatedAt))
+		require.NotEqual(t, last.ID, post.ID)
+
+	}
+
+}
+
+func TestListPostsSorted(t *testing.T) {
+
+	user := createRandomUser(t)
+	owner := sql.NullString{String: user.UserName, Valid: true}
+
+	posts := make([]Post, 3)
+	for i := range posts {
+
+		post, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+			Owner:    user.UserName,
+			Image:    util.RandomImage(),
+			Title:    util.RandomTitle(),
+			Subtitle: util.RandomSubtitle(),
+			Content:  util.RandomContent(),
+		})
+		require.NoError(t, err)
+		posts[i] = post
+
+	}
+
+	// The last post gets the most comments and the first one none
+	createRandomComment(t, posts[2], sql.NullInt64{})
+	createRandomComment(t, posts[2], sql.NullInt64{})
+	createRandomComment(t, posts[1], sql.NullInt64{})
+
+	byTitle, err := testQueries.ListPosts(context.Background(), ListPostsParams{
+		Owner:   owner,
+		SortKey: "title",
+		Limit:   5,
+	})
+	require.NoError(t, err)
+	require.Len(t, byTitle, 3)
+	for i := 1; i < len(byTitle); i++ {
+		require.LessOrEqual(t, byTitle[i-1].Title, byTitle[i].Title)
+	}
+
+	newest, err := testQueries.ListPosts(context.Background(), ListPostsParams{
+		Owner:    owner,
+		SortKey:  "created_at",
+		SortDesc: true,
+		Limit:    5,
+	})
+	require.NoError(t, err)
+	require.Len(t, newest, 3)
+	require.Equal(t, posts[2].ID, newest[0].ID)
+
+	mostCommented, err := testQueries.ListPosts(context.Background(), ListPostsParams{
+		Owner:    owner,
+		SortKey:  "comments",
+		SortDesc: true,
+		Limit:    5,
+	})
+	require.NoError(t, err)
+	require.Len(t, mostCommented, 3)
+	require.Equal(t, posts[2].ID, mostCommented[0].ID)
+	require.Equal(t, posts[1].ID, mostCommented[1].ID)
+	require.Equal(t, posts[0].ID, mostCommented[2].ID)
+
+	count, err := testQueries.CountPosts(context.Background(), CountPostsParams{
+		Owner:        owner,
+		CreatedAfter: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+}
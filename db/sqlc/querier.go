@@ -6,18 +6,87 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
-	CountPosts(ctx context.Context) (int64, error)
+	AddPostTag(ctx context.Context, arg AddPostTagParams) error
+	BlockSession(ctx context.Context, id uuid.UUID) error
+	BlockSessionsByUser(ctx context.Context, userName string) error
+	CountPosts(ctx context.Context, arg CountPostsParams) (int64, error)
+	CountSearchPosts(ctx context.Context, query string) (int64, error)
+	CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error)
+	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
+	CreateComment(ctx context.Context, arg CreateCommentParams) (Comment, error)
+	CreateDeletedUser(ctx context.Context, arg CreateDeletedUserParams) (DeletedUser, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
 	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	CreatePostRevision(ctx context.Context, arg CreatePostRevisionParams) (PostRevision, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	DeleteApiKey(ctx context.Context, arg DeleteApiKeyParams) (int64, error)
+	DeleteCategory(ctx context.Context, id int64) error
+	DeleteComment(ctx context.Context, id int64) error
+	DeleteCommentsByAuthor(ctx context.Context, author string) error
+	DeleteExpiredRevokedTokens(ctx context.Context) error
 	DeletePost(ctx context.Context, id int64) error
+	DeletePostsByOwner(ctx context.Context, owner string) error
+	DeleteRecoveryCodesByUser(ctx context.Context, userName string) error
+	DeleteSessionsByUser(ctx context.Context, userName string) error
+	DeleteStaleRateLimitBuckets(ctx context.Context, updatedBefore time.Time) (int64, error)
+	DeleteUser(ctx context.Context, userName string) error
+	EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (User, error)
+	GetApiKeyByHash(ctx context.Context, keyHash string) (GetApiKeyByHashRow, error)
+	GetCategory(ctx context.Context, id int64) (Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (Category, error)
+	GetComment(ctx context.Context, id int64) (Comment, error)
+	GetDeletedPost(ctx context.Context, id int64) (Post, error)
 	GetPost(ctx context.Context, id int64) (Post, error)
+	GetPostForUpdate(ctx context.Context, id int64) (Post, error)
+	GetPostRevision(ctx context.Context, arg GetPostRevisionParams) (PostRevision, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetUser(ctx context.Context, userName string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByIdentity(ctx context.Context, arg GetUserByIdentityParams) (User, error)
+	GetUserProfile(ctx context.Context, userName string) (GetUserProfileRow, error)
+	ListApiKeysByUser(ctx context.Context, userName string) ([]ApiKey, error)
+	ListCategories(ctx context.Context) ([]Category, error)
+	ListCommentsByPost(ctx context.Context, postID int64) ([]Comment, error)
+	ListDeletedPostsByOwner(ctx context.Context, arg ListDeletedPostsByOwnerParams) ([]Post, error)
+	ListPostRevisions(ctx context.Context, arg ListPostRevisionsParams) ([]PostRevision, error)
 	ListPosts(ctx context.Context, arg ListPostsParams) ([]Post, error)
+	ListPostsAfter(ctx context.Context, arg ListPostsAfterParams) ([]Post, error)
+	ListRevokedTokens(ctx context.Context) ([]RevokedToken, error)
+	ListTags(ctx context.Context) ([]ListTagsRow, error)
+	ListTagsByPost(ctx context.Context, postID int64) ([]Tag, error)
+	ListTokenGenerations(ctx context.Context) ([]ListTokenGenerationsRow, error)
+	ListUserIdentitiesByUser(ctx context.Context, userName string) ([]UserIdentity, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	PartialUpdatePost(ctx context.Context, arg PartialUpdatePostParams) (Post, error)
+	PurgeDeletedPosts(ctx context.Context, deletedBefore time.Time) (int64, error)
+	ResetUserPassword(ctx context.Context, arg ResetUserPasswordParams) (User, error)
+	RestorePost(ctx context.Context, id int64) (Post, error)
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error)
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error)
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error)
+	UpdateApiKeyLastUsed(ctx context.Context, id uuid.UUID) error
+	UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error)
+	UpdateComment(ctx context.Context, arg UpdateCommentParams) (Comment, error)
 	UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
+	UpsertTag(ctx context.Context, name string) (Tag, error)
+	UsePasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeRateLimitToken(t *testing.T) {
+
+	arg := TakeRateLimitTokenParams{
+
+		Key:   util.RandomString(16),
+		Burst: 2,
+		Rate:  1.0 / 3600,
+	}
+
+	row, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, row.Allowed)
+	require.InDelta(t, 1, row.Tokens, 0.01)
+
+	row, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, row.Allowed)
+	require.InDelta(t, 0, row.Tokens, 0.01)
+
+	// An empty bucket refuses without going below zero
+	row, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, row.Allowed)
+	require.InDelta(t, 0, row.Tokens, 0.01)
+
+}
+
+func TestDeleteStaleRateLimitBuckets(t *testing.T) {
+
+	arg := TakeRateLimitTokenParams{
+
+		Key:   util.RandomString(16),
+		Burst: 1,
+		Rate:  1,
+	}
+
+	_, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	deleted, err := testQueries.DeleteStaleRateLimitBuckets(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	// The bucket starts full again
+	row, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, row.Allowed)
+
+}
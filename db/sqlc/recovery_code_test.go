@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseRecoveryCode(t *testing.T) {
+
+	user := createRandomUser(t)
+	arg := CreateRecoveryCodeParams{
+
+		UserName: user.UserName,
+		CodeHash: util.HashToken(util.RandomString(16)),
+	}
+
+	err := testQueries.CreateRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+
+	code, err := testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		UserName: arg.UserName,
+		CodeHash: arg.CodeHash,
+	})
+	require.NoError(t, err)
+	require.True(t, code.UsedAt.Valid)
+
+	// A code only works once
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		UserName: arg.UserName,
+		CodeHash: arg.CodeHash,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestDeleteRecoveryCodesByUser(t *testing.T) {
+
+	user := createRandomUser(t)
+	arg := CreateRecoveryCodeParams{
+
+		UserName: user.UserName,
+		CodeHash: util.HashToken(util.RandomString(16)),
+	}
+
+	err := testQueries.CreateRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+
+	err = testQueries.DeleteRecoveryCodesByUser(context.Background(), user.UserName)
+	require.NoError(t, err)
+
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		UserName: arg.UserName,
+		CodeHash: arg.CodeHash,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func revokeRandomToken(t *testing.T, expiredAt time.Time) RevokeTokenParams {
+	user := createRandomUser(t)
+
+	arg := RevokeTokenParams{
+
+		ID:        uuid.New(),
+		UserName:  user.UserName,
+		ExpiredAt: expiredAt,
+	}
+
+	err := testQueries.RevokeToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	return arg
+
+}
+
+func findRevokedToken(tokens []RevokedToken, id uuid.UUID) *RevokedToken {
+
+	for i := range tokens {
+
+		if tokens[i].ID == id {
+			return &tokens[i]
+		}
+
+	}
+
+	return nil
+
+}
+
+func TestRevokeToken(t *testing.T) {
+
+	arg := revokeRandomToken(t, time.Now().Add(time.Minute))
+
+	// Revoking the same token twice must not fail
+	err := testQueries.RevokeToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	tokens, err := testQueries.ListRevokedTokens(context.Background())
+	require.NoError(t, err)
+
+	revoked := findRevokedToken(tokens, arg.ID)
+	require.NotNil(t, revoked)
+	require.Equal(t, arg.UserName, revoked.UserName)
+	require.WithinDuration(t, arg.ExpiredAt, revoked.ExpiredAt, time.Second)
+	require.NotZero(t, revoked.RevokedAt)
+
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+
+	expired := revokeRandomToken(t, time.Now().Add(-time.Minute))
+	active := revokeRandomToken(t, time.Now().Add(time.Minute))
+
+	err := testQueries.DeleteExpiredRevokedTokens(context.Background())
+	require.NoError(t, err)
+
+	tokens, err := testQueries.ListRevokedTokens(context.Background())
+	require.NoError(t, err)
+
+	require.Nil(t, findRevokedToken(tokens, expired.ID))
+	require.NotNil(t, findRevokedToken(tokens, active.ID))
+
+}
@@ -1,11 +1,21 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 )
 
 type Store interface {
 	Querier
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error)
+	CreatePostWithTagsTx(ctx context.Context, arg CreatePostWithTagsTxParams) (CreatePostWithTagsTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	CreateUserWithIdentityTx(ctx context.Context, arg CreateUserWithIdentityTxParams) (CreateUserWithIdentityTxResult, error)
+	DeleteUserTx(ctx context.Context, userName string) (DeleteUserTxResult, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (EnableTOTPTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
+	UpdatePostTx(ctx context.Context, arg PartialUpdatePostParams) (UpdatePostTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
 }
 
 //Store will allow DB execute queries and transactions for all functions
@@ -0,0 +1,461 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePostWithTagsTx(t *testing.T) {
+
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	existing := createRandomTag(t)
+
+	arg := CreatePostWithTagsTxParams{
+		CreatePostParams: CreatePostParams{
+			Owner:    user.UserName,
+			Image:    util.RandomImage(),
+			Title:    util.RandomTitle(),
+			Subtitle: util.RandomSubtitle(),
+			Content:  util.RandomContent(),
+		},
+		Tags: []string{existing.Name, util.RandomString(10)},
+	}
+
+	result, err := store.CreatePostWithTagsTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, result.Post.ID)
+	require.Equal(t, arg.Title, result.Post.Title)
+	require.Len(t, result.Tags, 2)
+	require.Equal(t, existing.ID, result.Tags[0].ID)
+
+	tags, err := testQueries.ListTagsByPost(context.Background(), result.Post.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+
+}
+
+func TestCreatePostWithTagsTxRollback(t *testing.T) {
+
+	store := NewStore(testDB)
+	post := createRandomPost(t)
+
+	// Same title and owner as an existing post, so the whole transaction fails
+	arg := CreatePostWithTagsTxParams{
+		CreatePostParams: CreatePostParams{
+			Owner:    post.Owner,
+			Image:    post.Image,
+			Title:    post.Title,
+			Subtitle: post.Subtitle,
+			Content:  post.Content,
+		},
+		Tags: []string{util.RandomString(10)},
+	}
+
+	_, err := store.CreatePostWithTagsTx(context.Background(), arg)
+	require.Error(t, err)
+
+	var pqErr *pq.Error
+	require.True(t, errors.As(err, &pqErr))
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+
+	tags, err := testQueries.ListTags(context.Background())
+	require.NoError(t, err)
+	for _, tag := range tags {
+		require.NotEqual(t, arg.Tags[0], tag.Name)
+	}
+
+}
+
+func TestDeleteUserTx(t *testing.T) {
+
+	store := NewStore(testDB)
+
+	post := createRandomPost(t)
+	comment := createRandomComment(t, post, sql.NullInt64{})
+	other := createRandomPost(t)
+	reply := createRandomComment(t, other, sql.NullInt64{})
+
+	// The post owner also commented on somebody else's post
+	_, err := testQueries.CreateComment(context.Background(), CreateCommentParams{
+		PostID: other.ID,
+		Author: post.Owner,
+		Body:   util.RandomContent(),
+	})
+	require.NoError(t, err)
+
+	revoked := RevokeTokenParams{
+		ID:        uuid.New(),
+		UserName:  post.Owner,
+		ExpiredAt: time.Now().Add(time.Minute),
+	}
+	err = testQueries.RevokeToken(context.Background(), revoked)
+	require.NoError(t, err)
+
+	owner, err := testQueries.GetUser(context.Background(), post.Owner)
+	require.NoError(t, err)
+
+	result, err := store.DeleteUserTx(context.Background(), post.Owner)
+	require.NoError(t, err)
+	require.Equal(t, post.Owner, result.DeletedUser.UserName)
+	require.Equal(t, owner.TokenGeneration+1, result.DeletedUser.TokenGeneration)
+
+	_, err = testQueries.GetUser(context.Background(), post.Owner)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Tokens of the deleted user stay invalid
+	generations, err := testQueries.ListTokenGenerations(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, generations, ListTokenGenerationsRow{UserName: post.Owner, TokenGeneration: owner.TokenGeneration + 1})
+
+	// Revocations stay until the token expires
+	tokens, err := testQueries.ListRevokedTokens(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, findRevokedToken(tokens, revoked.ID))
+
+	_, err = testQueries.GetPost(context.Background(), post.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testQueries.GetComment(context.Background(), comment.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Content of other users is left alone
+	_, err = testQueries.GetPost(context.Background(), other.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetComment(context.Background(), reply.ID)
+	require.NoError(t, err)
+
+	comments, err := testQueries.ListCommentsByPost(context.Background(), other.ID)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+
+}
+
+func TestDeleteUserTxNameTakenAgain(t *testing.T) {
+
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	result, err := store.DeleteUserTx(context.Background(), user.UserName)
+	require.NoError(t, err)
+
+	// A new account with the name starts where the old one ended, old tokens do not work for it
+	user2, err := testQueries.CreateUser(context.Background(), CreateUserParams{
+		UserName:       user.UserName,
+		HashedPassword: user.HashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, result.DeletedUser.TokenGeneration, user2.TokenGeneration)
+
+	generations, err := testQueries.ListTokenGenerations(context.Background())
+	require.NoError(t, err)
+
+	count := 0
+	for _, generation := range generations {
+		if generation.UserName == user.UserName {
+			count++
+		}
+	}
+	require.Equal(t, 1, count)
+
+}
+
+func TestDeleteUserTxNotFound(t *testing.T) {
+
+	store := NewStore(testDB)
+
+	_, err := store.DeleteUserTx(context.Background(), util.RandomOwner())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+}
+
+func TestUpdatePostTx(t *testing.T) {
+
+	store := NewStore(testDB)
+	post := createRandomPost(t)
+
+	n := 5
+	errs := make(chan error)
+
+	// Concurrent edits each get their own revision number
+	for i := 0; i < n; i++ {
+
+		go func() {
+			_, err := store.UpdatePostTx(context.Background(), PartialUpdatePostParams{
+				ID:      post.ID,
+				Content: sql.NullString{String: util.RandomContent(), Valid: true},
+			})
+			errs <- err
+		}()
+
+	}
+
+	for i := 0; i < n; i++ {
+
+		require.NoError(t, <-errs)
+
+	}
+
+	revisions, err := testQueries.ListPostRevisions(context.Background(), ListPostRevisionsParams{
+		PostID: post.ID,
+		Limit:  int32(n),
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, revisions, n)
+	require.Equal(t, int32(n), revisions[0].Revision)
+
+	// The first revision is the post as it was created
+	require.Equal(t, post.Content, revisions[n-1].Content)
+	require.Equal(t, post.Title, revisions[n-1].Title)
+
+}
+
+func TestUpdatePostTxNotFound(t *testing.T) {
+
+	store := NewStore(testDB)
+	post := createRandomPost(t)
+
+	err := testQueries.DeletePost(context.Background(), post.ID)
+	require.NoError(t, err)
+
+	_, err = store.UpdatePostTx(context.Background(), PartialUpdatePostParams{
+		ID:      post.ID,
+		Content: sql.NullString{String: util.RandomContent(), Valid: true},
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+}
+
+func TestCreateUserTx(t *testing.T) {
+
+	store := NewStore(testDB)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			UserName:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		SecretCode:        util.RandomString(32),
+		VerifyEmailExpiry: time.Now().Add(time.Hour),
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.False(t, result.User.IsEmailVerified)
+	require.Equal(t, result.User.UserName, result.VerifyEmail.UserName)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.SecretCode, result.VerifyEmail.SecretCode)
+
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	verifyEmail := createRandomVerifyEmail(t, user, time.Now().Add(time.Hour))
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.NoError(t, err)
+
+	require.True(t, result.User.IsEmailVerified)
+	require.True(t, result.VerifyEmail.IsUsed)
+
+}
+
+func TestVerifyEmailTxChangedEmail(t *testing.T) {
+
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	verifyEmail := createRandomVerifyEmail(t, user, time.Now().Add(time.Hour))
+
+	_, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Email:    sql.NullString{String: util.RandomEmail(), Valid: true},
+		UserName: user.UserName,
+	})
+	require.NoError(t, err)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	user, err = testQueries.GetUser(context.Background(), user.UserName)
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+
+}
+
+func TestResetPasswordTx(t *testing.T) {
+
+	store := NewStore(testDB)
+	session := createRandomSession(t)
+
+	user, err := testQueries.GetUser(context.Background(), session.UserName)
+	require.NoError(t, err)
+	reset := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		TokenHash:      reset.TokenHash,
+		HashedPassword: hashedPassword,
+	}
+
+	result, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.Equal(t, user.TokenGeneration+1, result.User.TokenGeneration)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	// The token cannot be used a second time
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+}
+
+func TestChangePasswordTx(t *testing.T) {
+
+	store := NewStore(testDB)
+	session := createRandomSession(t)
+
+	user, err := testQueries.GetUser(context.Background(), session.UserName)
+	require.NoError(t, err)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	result, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		UserName:       user.UserName,
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.Equal(t, user.TokenGeneration+1, result.User.TokenGeneration)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	_, err = store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		UserName:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+}
+
+func TestEnableTOTPTx(t *testing.T) {
+
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	secret, err := util.NewTOTPSecret()
+	require.NoError(t, err)
+
+	_, err = testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		UserName:   user.UserName,
+		TotpSecret: secret,
+	})
+	require.NoError(t, err)
+
+	hashes := []string{util.HashToken(util.RandomString(16)), util.HashToken(util.RandomString(16))}
+	result, err := store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		UserName:           user.UserName,
+		Step:               1,
+		RecoveryCodeHashes: hashes,
+	})
+	require.NoError(t, err)
+	require.True(t, result.User.IsTotpEnabled)
+
+	for _, hash := range hashes {
+		_, err := testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+			UserName: user.UserName,
+			CodeHash: hash,
+		})
+		require.NoError(t, err)
+	}
+
+}
+
+func TestCreateUserWithIdentityTx(t *testing.T) {
+
+	store := NewStore(testDB)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserWithIdentityTxParams{
+		CreateUserParams: CreateUserParams{
+			UserName:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		Provider: util.RandomString(6),
+		Subject:  util.RandomString(16),
+	}
+
+	result, err := store.CreateUserWithIdentityTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.UserName, result.User.UserName)
+	require.True(t, result.User.IsEmailVerified)
+	require.Equal(t, arg.UserName, result.UserIdentity.UserName)
+	require.Equal(t, arg.Email, result.UserIdentity.Email)
+
+	user, err := testQueries.GetUserByIdentity(context.Background(), GetUserByIdentityParams{
+		Provider: arg.Provider,
+		Subject:  arg.Subject,
+	})
+	require.NoError(t, err)
+	require.Equal(t, result.User.ID, user.ID)
+
+	// The user is rolled back when the identity is already linked
+	arg.UserName = util.RandomOwner()
+	arg.Email = util.RandomEmail()
+
+	_, err = store.CreateUserWithIdentityTx(context.Background(), arg)
+	require.Error(t, err)
+
+	_, err = testQueries.GetUser(context.Background(), arg.UserName)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+
+	require.True(t, isSerializationFailure(&pq.Error{Code: "40001"}))
+	require.True(t, isSerializationFailure(&pq.Error{Code: "40P01"}))
+	require.False(t, isSerializationFailure(&pq.Error{Code: "23505"}))
+	require.False(t, isSerializationFailure(sql.ErrNoRows))
+	require.False(t, isSerializationFailure(nil))
+
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomTag(t *testing.T) Tag {
+
+	name := util.RandomString(10)
+
+	tag, err := testQueries.UpsertTag(context.Background(), name)
+	require.NoError(t, err)
+	require.NotEmpty(t, tag)
+
+	require.Equal(t, name, tag.Name)
+	require.NotZero(t, tag.ID)
+	require.NotZero(t, tag.CreatedAt)
+
+	return tag
+
+}
+
+func TestUpsertTag(t *testing.T) {
+
+	tag1 := createRandomTag(t)
+
+	tag2, err := testQueries.UpsertTag(context.Background(), tag1.Name)
+	require.NoError(t, err)
+	require.Equal(t, tag1.ID, tag2.ID)
+
+}
+
+func TestListTagsByPost(t *testing.T) {
+
+	post := createRandomPost(t)
+	tag1 := createRandomTag(t)
+	tag2 := createRandomTag(t)
+
+	for _, tag := range []Tag{tag1, tag2, tag1} {
+
+		err := testQueries.AddPostTag(context.Background(), AddPostTagParams{
+			PostID: post.ID,
+			TagID:  tag.ID,
+		})
+		require.NoError(t, err)
+
+	}
+
+	tags, err := testQueries.ListTagsByPost(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+
+}
+
+func TestListPostsByTags(t *testing.T) {
+
+	post1 := createRandomPost(t)
+	post2 := createRandomPost(t)
+	tag1 := createRandomTag(t)
+	tag2 := createRandomTag(t)
+
+	links := []AddPostTagParams{
+		{PostID: post1.ID, TagID: tag1.ID},
+		{PostID: post1.ID, TagID: tag2.ID},
+		{PostID: post2.ID, TagID: tag1.ID},
+	}
+	for _, link := range links {
+
+		err := testQueries.AddPostTag(context.Background(), link)
+		require.NoError(t, err)
+
+	}
+
+	names := []string{tag1.Name, tag2.Name}
+
+	anyCount, err := testQueries.CountPosts(context.Background(), CountPostsParams{Tags: names})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), anyCount)
+
+	allPosts, err := testQueries.ListPosts(context.Background(), ListPostsParams{
+		Tags:     names,
+		MatchAll: true,
+		Limit:    5,
+		Offset:   0,
+	})
+	require.NoError(t, err)
+	require.Len(t, allPosts, 1)
+	require.Equal(t, post1.ID, allPosts[0].ID)
+
+}
+
+func TestListTags(t *testing.T) {
+
+	post := createRandomPost(t)
+	tag := createRandomTag(t)
+
+	err := testQueries.AddPostTag(context.Background(), AddPostTagParams{
+		PostID: post.ID,
+		TagID:  tag.ID,
+	})
+	require.NoError(t, err)
+
+	tags, err := testQueries.ListTags(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, tags)
+
+	for _, row := range tags {
+		if row.ID == tag.ID {
+			require.Equal(t, int64(1), row.PostCount)
+		}
+	}
+
+}
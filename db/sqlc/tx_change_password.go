@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+//Input parameters of the ChangePassword transaction
+type ChangePasswordTxParams struct {
+	UserName       string
+	HashedPassword string
+}
+
+//Result of the ChangePassword transaction
+type ChangePasswordTxResult struct {
+	User User `json:"user"`
+}
+
+//ChangePasswordTx stores the new password of a user and bumps their token generation.
+//Like a reset it blocks the sessions of the user, a password is often changed because it leaked.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error) {
+
+	var result ChangePasswordTxResult
+
+	err := store.execTx(ctx, sql.LevelReadCommitted, func(q *Queries) error {
+
+		var err error
+
+		result.User, err = q.ResetUserPassword(ctx, ResetUserPasswordParams{
+			UserName:       arg.UserName,
+			HashedPassword: arg.HashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.BlockSessionsByUser(ctx, arg.UserName)
+
+	})
+
+	return result, err
+
+}
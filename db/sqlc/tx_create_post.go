@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+//Input parameters of the CreatePostWithTags transaction
+type CreatePostWithTagsTxParams struct {
+	CreatePostParams
+	Tags []string `json:"tags"`
+}
+
+//Result of the CreatePostWithTags transaction
+type CreatePostWithTagsTxResult struct {
+	Post Post  `json:"post"`
+	Tags []Tag `json:"tags"`
+}
+
+//CreatePostWithTagsTx creates the post, the tags that don't exist yet and links them together,
+//so a post is never left with only part of its tags
+func (store *SQLStore) CreatePostWithTagsTx(ctx context.Context, arg CreatePostWithTagsTxParams) (CreatePostWithTagsTxResult, error) {
+
+	var result CreatePostWithTagsTxResult
+
+	err := store.execTx(ctx, sql.LevelReadCommitted, func(q *Queries) error {
+
+		var err error
+
+		result.Post, err = q.CreatePost(ctx, arg.CreatePostParams)
+		if err != nil {
+			return err
+		}
+
+		result.Tags = []Tag{}
+
+		for _, name := range arg.Tags {
+
+			tag, err := q.UpsertTag(ctx, name)
+			if err != nil {
+				return err
+			}
+
+			err = q.AddPostTag(ctx, AddPostTagParams{
+				PostID: result.Post.ID,
+				TagID:  tag.ID,
+			})
+			if err != nil {
+				return err
+			}
+
+			result.Tags = append(result.Tags, tag)
+
+		}
+
+		return nil
+
+	})
+
+	return result, err
+
+}
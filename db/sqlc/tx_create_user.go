@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+//Input parameters of the CreateUser transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	SecretCode        string
+	VerifyEmailExpiry time.Time
+}
+
+//Result of the CreateUser transaction
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+//CreateUserTx creates the user together with the code that verifies their email address,
+//so an account is never left without a way to be verified
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, sql.LevelReadCommitted, func(q *Queries) error {
+
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			UserName:   result.User.UserName,
+			Email:      result.User.Email,
+			SecretCode: arg.SecretCode,
+			ExpiredAt:  arg.VerifyEmailExpiry,
+		})
+		return err
+
+	})
+
+	return result, err
+
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+//Input parameters of the CreateUserWithIdentity transaction
+type CreateUserWithIdentityTxParams struct {
+	CreateUserParams
+	Provider string
+	Subject  string
+}
+
+//Result of the CreateUserWithIdentity transaction
+type CreateUserWithIdentityTxResult struct {
+	User         User         `json:"user"`
+	UserIdentity UserIdentity `json:"user_identity"`
+}
+
+//CreateUserWithIdentityTx creates a user that signed up through an OpenID provider and links the identity to them.
+//The provider has already verified the email address, so the user starts out verified.
+func (store *SQLStore) CreateUserWithIdentityTx(ctx context.Context, arg CreateUserWithIdentityTxParams) (CreateUserWithIdentityTxResult, error) {
+
+	var result CreateUserWithIdentityTxResult
+
+	err := store.execTx(ctx, sql.LevelReadCommitted, func(q *Queries) error {
+
+		user, err := q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			UserName: user.UserName,
+			Email:    user.Email,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.UserIdentity, err = q.CreateUserIdentity(ctx, CreateUserIdentityParams{
+			UserName: user.UserName,
+			Provider: arg.Provider,
+			Subject:  arg.Subject,
+			Email:    user.Email,
+		})
+
+		return err
+
+	})
+
+	return result, err
+
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+//Result of the DeleteUser transaction
+type DeleteUserTxResult struct {
+	DeletedUser DeletedUser `json:"deleted_user"`
+}
+
+//DeleteUserTx removes the user together with everything that references it:
+//their comments, their posts (with the comments and tags of those posts) and sessions.
+//The user name is kept as a deleted user one token generation ahead, so every token
+//of the user stays invalid, also for a new account that takes the name later.
+//Revoked tokens are kept until they expire, they must stay rejected after the user is gone.
+//It runs serializable so a post created concurrently cannot keep the user from being deleted.
+func (store *SQLStore) DeleteUserTx(ctx context.Context, userName string) (DeleteUserTxResult, error) {
+
+	var result DeleteUserTxResult
+
+	err := store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+
+		user, err := q.GetUser(ctx, userName)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteCommentsByAuthor(ctx, userName)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeletePostsByOwner(ctx, userName)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteSessionsByUser(ctx, userName)
+		if err != nil {
+			return err
+		}
+
+		result.DeletedUser, err = q.CreateDeletedUser(ctx, CreateDeletedUserParams{
+			UserName:        userName,
+			TokenGeneration: user.TokenGeneration + 1,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteUser(ctx, userName)
+
+	})
+
+	return result, err
+
+}
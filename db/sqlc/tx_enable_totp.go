@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+//Input parameters of the EnableTOTP transaction
+type EnableTOTPTxParams struct {
+	UserName string
+	//Step of the code that confirmed the secret, it cannot be used again to log in
+	Step               int64
+	RecoveryCodeHashes []string
+}
+
+//Result of the EnableTOTP transaction
+type EnableTOTPTxResult struct {
+	User User `json:"user"`
+}
+
+//EnableTOTPTx turns on two-factor authentication for the user and replaces the recovery codes with new ones
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (EnableTOTPTxResult, error) {
+
+	var result EnableTOTPTxResult
+
+	err := store.execTx(ctx, sql.LevelReadCommitted, func(q *Queries) error {
+
+		var err error
+
+		result.User, err = q.EnableUserTOTP(ctx, EnableUserTOTPParams{
+			UserName:     arg.UserName,
+			TotpLastStep: arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecoveryCodesByUser(ctx, arg.UserName)
+		if err != nil {
+			return err
+		}
+
+		for _, codeHash := range arg.RecoveryCodeHashes {
+
+			err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				UserName: arg.UserName,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+
+		}
+
+		return nil
+
+	})
+
+	return result, err
+
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+//Input parameters of the ResetPassword transaction
+type ResetPasswordTxParams struct {
+	TokenHash      string
+	HashedPassword string
+}
+
+//Result of the ResetPassword transaction
+type ResetPasswordTxResult struct {
+	User User `json:"user"`
+}
+
+//ResetPasswordTx uses up the reset token, stores the new password and bumps the token generation of the user.
+//The sessions of the user are blocked so the refresh tokens issued before the reset stop working too.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+
+	var result ResetPasswordTxResult
+
+	err := store.execTx(ctx, sql.LevelReadCommitted, func(q *Queries) error {
+
+		reset, err := q.UsePasswordReset(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.ResetUserPassword(ctx, ResetUserPasswordParams{
+			UserName:       reset.UserName,
+			HashedPassword: arg.HashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.BlockSessionsByUser(ctx, reset.UserName)
+
+	})
+
+	return result, err
+
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+//Result of the UpdatePost transaction
+type UpdatePostTxResult struct {
+	Post     Post         `json:"post"`
+	Revision PostRevision `json:"revision"`
+}
+
+//UpdatePostTx records the current version of the post as a new revision and then applies the update.
+//The post row stays locked until commit, so concurrent edits cannot claim the same revision number.
+func (store *SQLStore) UpdatePostTx(ctx context.Context, arg PartialUpdatePostParams) (UpdatePostTxResult, error) {
+
+	var result UpdatePostTxResult
+
+	err := store.execTx(ctx, sql.LevelReadCommitted, func(q *Queries) error {
+
+		post, err := q.GetPostForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		result.Revision, err = q.CreatePostRevision(ctx, CreatePostRevisionParams{
+			PostID:   post.ID,
+			Image:    post.Image,
+			Title:    post.Title,
+			Subtitle: post.Subtitle,
+			Content:  post.Content,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Post, err = q.PartialUpdatePost(ctx, arg)
+		return err
+
+	})
+
+	return result, err
+
+}
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -14,11 +16,13 @@ INSERT INTO users (
   user_name,
   hashed_password,
   full_name,
-  email
+  email,
+  token_generation
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4,
+  COALESCE((SELECT token_generation FROM deleted_users WHERE deleted_users.user_name = $1), 0)
 )
-RETURNING id, user_name, hashed_password, full_name, email, created_at
+RETURNING id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step
 `
 
 type CreateUserParams struct {
@@ -43,12 +47,62 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.FullName,
 		&i.Email,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE user_name = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, userName string) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, userName)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :one
+UPDATE users
+SET
+  is_totp_enabled = TRUE,
+  totp_last_step = $2
+WHERE user_name = $1 AND totp_secret <> ''
+RETURNING id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step
+`
+
+type EnableUserTOTPParams struct {
+	UserName     string `json:"user_name"`
+	TotpLastStep int64  `json:"totp_last_step"`
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableUserTOTP, arg.UserName, arg.TotpLastStep)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserName,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, user_name, hashed_password, full_name, email, created_at FROM users
+SELECT id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step FROM users
 WHERE user_name = $1 LIMIT 1
 `
 
@@ -62,12 +116,111 @@ func (q *Queries) GetUser(ctx context.Context, userName string) (User, error) {
 		&i.FullName,
 		&i.Email,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserName,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT
+  user_name,
+  full_name,
+  role,
+  created_at,
+  (SELECT COUNT(*) FROM posts WHERE posts.owner = users.user_name AND posts.deleted_at IS NULL) AS post_count
+FROM users
+WHERE user_name = $1 LIMIT 1
+`
+
+type GetUserProfileRow struct {
+	UserName  string    `json:"user_name"`
+	FullName  string    `json:"full_name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	PostCount int64     `json:"post_count"`
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, userName string) (GetUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfile, userName)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.UserName,
+		&i.FullName,
+		&i.Role,
+		&i.CreatedAt,
+		&i.PostCount,
+	)
+	return i, err
+}
+
+const listTokenGenerations = `-- name: ListTokenGenerations :many
+SELECT user_name, token_generation FROM users
+WHERE token_generation > 0
+UNION ALL
+SELECT user_name, token_generation FROM deleted_users
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE users.user_name = deleted_users.user_name)
+`
+
+type ListTokenGenerationsRow struct {
+	UserName        string `json:"user_name"`
+	TokenGeneration int32  `json:"token_generation"`
+}
+
+func (q *Queries) ListTokenGenerations(ctx context.Context) ([]ListTokenGenerationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTokenGenerations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTokenGenerationsRow{}
+	for rows.Next() {
+		var i ListTokenGenerationsRow
+		if err := rows.Scan(&i.UserName, &i.TokenGeneration); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT id, user_name, hashed_password, full_name, email, created_at FROM users
+SELECT id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step FROM users
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -94,6 +247,12 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.FullName,
 			&i.Email,
 			&i.CreatedAt,
+			&i.Role,
+			&i.IsEmailVerified,
+			&i.TokenGeneration,
+			&i.TotpSecret,
+			&i.IsTotpEnabled,
+			&i.TotpLastStep,
 		); err != nil {
 			return nil, err
 		}
@@ -107,3 +266,188 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 	}
 	return items, nil
 }
+
+const resetUserPassword = `-- name: ResetUserPassword :one
+UPDATE users
+SET
+  hashed_password = $2,
+  token_generation = token_generation + 1
+WHERE user_name = $1
+RETURNING id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step
+`
+
+type ResetUserPasswordParams struct {
+	UserName       string `json:"user_name"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+func (q *Queries) ResetUserPassword(ctx context.Context, arg ResetUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, resetUserPassword, arg.UserName, arg.HashedPassword)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserName,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :one
+UPDATE users
+SET totp_secret = $2
+WHERE user_name = $1 AND is_totp_enabled = FALSE
+RETURNING id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step
+`
+
+type SetUserTOTPSecretParams struct {
+	UserName   string `json:"user_name"`
+	TotpSecret string `json:"totp_secret"`
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserTOTPSecret, arg.UserName, arg.TotpSecret)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserName,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET
+  full_name = COALESCE($1, full_name),
+  email = COALESCE($2, email),
+  is_email_verified = is_email_verified AND COALESCE($2, email) = email
+WHERE user_name = $3
+RETURNING id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step
+`
+
+type UpdateUserParams struct {
+	FullName sql.NullString `json:"full_name"`
+	Email    sql.NullString `json:"email"`
+	UserName string         `json:"user_name"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser, arg.FullName, arg.Email, arg.UserName)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserName,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE user_name = $1
+RETURNING id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step
+`
+
+type UpdateUserRoleParams struct {
+	UserName string `json:"user_name"`
+	Role     string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.UserName, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserName,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE user_name = $1 AND totp_last_step < $2
+`
+
+type UseUserTOTPStepParams struct {
+	UserName     string `json:"user_name"`
+	TotpLastStep int64  `json:"totp_last_step"`
+}
+
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useUserTOTPStep, arg.UserName, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = TRUE
+WHERE user_name = $1 AND email = $2
+RETURNING id, user_name, hashed_password, full_name, email, created_at, role, is_email_verified, token_generation, totp_secret, is_totp_enabled, totp_last_step
+`
+
+type VerifyUserEmailParams struct {
+	UserName string `json:"user_name"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.UserName, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserName,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TokenGeneration,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
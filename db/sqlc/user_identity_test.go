@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserIdentity(t *testing.T, user User) UserIdentity {
+
+	arg := CreateUserIdentityParams{
+
+		UserName: user.UserName,
+		Provider: util.RandomString(6),
+		Subject:  util.RandomString(16),
+		Email:    user.Email,
+	}
+
+	identity, err := testQueries.CreateUserIdentity(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, identity)
+
+	require.Equal(t, arg.UserName, identity.UserName)
+	require.Equal(t, arg.Provider, identity.Provider)
+	require.Equal(t, arg.Subject, identity.Subject)
+	require.Equal(t, arg.Email, identity.Email)
+	require.NotZero(t, identity.ID)
+	require.NotZero(t, identity.CreatedAt)
+
+	return identity
+
+}
+
+func TestCreateUserIdentity(t *testing.T) {
+
+	identity := createRandomUserIdentity(t, createRandomUser(t))
+
+	// A subject of a provider is linked to a single user
+	_, err := testQueries.CreateUserIdentity(context.Background(), CreateUserIdentityParams{
+		UserName: createRandomUser(t).UserName,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    util.RandomEmail(),
+	})
+	require.Error(t, err)
+
+}
+
+func TestGetUserByIdentity(t *testing.T) {
+
+	user1 := createRandomUser(t)
+	identity := createRandomUserIdentity(t, user1)
+
+	user2, err := testQueries.GetUserByIdentity(context.Background(), GetUserByIdentityParams{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.ID, user2.ID)
+	require.Equal(t, user1.UserName, user2.UserName)
+
+	_, err = testQueries.GetUserByIdentity(context.Background(), GetUserByIdentityParams{
+		Provider: util.RandomString(6),
+		Subject:  identity.Subject,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestListUserIdentitiesByUser(t *testing.T) {
+
+	user := createRandomUser(t)
+	for i := 0; i < 2; i++ {
+		createRandomUserIdentity(t, user)
+	}
+
+	identities, err := testQueries.ListUserIdentitiesByUser(context.Background(), user.UserName)
+	require.NoError(t, err)
+	require.Len(t, identities, 2)
+
+	for _, identity := range identities {
+		require.Equal(t, user.UserName, identity.UserName)
+	}
+
+}
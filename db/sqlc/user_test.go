@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -29,6 +30,7 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, util.UserRole, user.Role)
 
 	require.NotZero(t, user.ID)
 	require.NotZero(t, user.CreatedAt)
@@ -84,3 +86,142 @@ func TestListUsers(t *testing.T) {
 	}
 
 }
+
+func TestUpdateUserRole(t *testing.T) {
+
+	user1 := createRandomUser(t)
+
+	args := UpdateUserRoleParams{
+
+		UserName: user1.UserName,
+		Role:     util.ModeratorRole,
+	}
+
+	user2, err := testQueries.UpdateUserRole(context.Background(), args)
+	require.NoError(t, err)
+	require.NotEmpty(t, user2)
+
+	require.Equal(t, user1.ID, user2.ID)
+	require.Equal(t, user1.UserName, user2.UserName)
+	require.Equal(t, args.Role, user2.Role)
+
+}
+
+func TestGetUserProfile(t *testing.T) {
+
+	post := createRandomPost(t)
+	deleted, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		Owner:    post.Owner,
+		Image:    util.RandomImage(),
+		Title:    util.RandomTitle(),
+		Subtitle: util.RandomSubtitle(),
+		Content:  util.RandomContent(),
+	})
+	require.NoError(t, err)
+
+	err = testQueries.DeletePost(context.Background(), deleted.ID)
+	require.NoError(t, err)
+
+	profile, err := testQueries.GetUserProfile(context.Background(), post.Owner)
+	require.NoError(t, err)
+
+	require.Equal(t, post.Owner, profile.UserName)
+	// Posts in the trash are not counted
+	require.Equal(t, int64(1), profile.PostCount)
+
+}
+
+func TestUpdateUser(t *testing.T) {
+
+	user1 := createRandomUser(t)
+	arg := UpdateUserParams{
+
+		Email:    sql.NullString{String: util.RandomEmail(), Valid: true},
+		UserName: user1.UserName,
+	}
+
+	user2, err := testQueries.UpdateUser(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.Email.String, user2.Email)
+	require.Equal(t, user1.FullName, user2.FullName)
+
+}
+
+func TestResetUserPassword(t *testing.T) {
+
+	user1 := createRandomUser(t)
+	require.Zero(t, user1.TokenGeneration)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	user2, err := testQueries.ResetUserPassword(context.Background(), ResetUserPasswordParams{
+		UserName:       user1.UserName,
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, hashedPassword, user2.HashedPassword)
+	require.Equal(t, int32(1), user2.TokenGeneration)
+
+	generations, err := testQueries.ListTokenGenerations(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, generations, ListTokenGenerationsRow{UserName: user1.UserName, TokenGeneration: 1})
+
+}
+
+func TestUserTOTP(t *testing.T) {
+
+	user1 := createRandomUser(t)
+	require.False(t, user1.IsTotpEnabled)
+
+	// Cannot be enabled before a secret is set
+	_, err := testQueries.EnableUserTOTP(context.Background(), EnableUserTOTPParams{
+		UserName:     user1.UserName,
+		TotpLastStep: 1,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	secret, err := util.NewTOTPSecret()
+	require.NoError(t, err)
+
+	user2, err := testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		UserName:   user1.UserName,
+		TotpSecret: secret,
+	})
+	require.NoError(t, err)
+	require.Equal(t, secret, user2.TotpSecret)
+	require.False(t, user2.IsTotpEnabled)
+
+	user3, err := testQueries.EnableUserTOTP(context.Background(), EnableUserTOTPParams{
+		UserName:     user1.UserName,
+		TotpLastStep: 10,
+	})
+	require.NoError(t, err)
+	require.True(t, user3.IsTotpEnabled)
+	require.Equal(t, int64(10), user3.TotpLastStep)
+
+	// The secret of an enabled account cannot be replaced
+	_, err = testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		UserName:   user1.UserName,
+		TotpSecret: secret,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	// Every step can only be used once
+	used, err := testQueries.UseUserTOTPStep(context.Background(), UseUserTOTPStepParams{
+		UserName:     user1.UserName,
+		TotpLastStep: 10,
+	})
+	require.NoError(t, err)
+	require.Zero(t, used)
+
+	used, err = testQueries.UseUserTOTPStep(context.Background(), UseUserTOTPStepParams{
+		UserName:     user1.UserName,
+		TotpLastStep: 11,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), used)
+
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomVerifyEmail(t *testing.T, user User, expiredAt time.Time) VerifyEmail {
+	arg := CreateVerifyEmailParams{
+
+		UserName:   user.UserName,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		ExpiredAt:  expiredAt,
+	}
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.UserName, verifyEmail.UserName)
+	require.Equal(t, arg.Email, verifyEmail.Email)
+	require.Equal(t, arg.SecretCode, verifyEmail.SecretCode)
+	require.False(t, verifyEmail.IsUsed)
+
+	require.NotZero(t, verifyEmail.ID)
+	require.NotZero(t, verifyEmail.CreatedAt)
+
+	return verifyEmail
+
+}
+
+func TestCreateVerifyEmail(t *testing.T) {
+
+	createRandomVerifyEmail(t, createRandomUser(t), time.Now().Add(time.Hour))
+
+}
+
+func TestUpdateVerifyEmail(t *testing.T) {
+
+	verifyEmail := createRandomVerifyEmail(t, createRandomUser(t), time.Now().Add(time.Hour))
+	arg := UpdateVerifyEmailParams{
+
+		ID:         verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	}
+
+	used, err := testQueries.UpdateVerifyEmail(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, used.IsUsed)
+
+	// A code only works once
+	_, err = testQueries.UpdateVerifyEmail(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestUpdateVerifyEmailExpired(t *testing.T) {
+
+	verifyEmail := createRandomVerifyEmail(t, createRandomUser(t), time.Now().Add(-time.Minute))
+
+	_, err := testQueries.UpdateVerifyEmail(context.Background(), UpdateVerifyEmailParams{
+		ID:         verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
+
+func TestUpdateVerifyEmailWrongCode(t *testing.T) {
+
+	verifyEmail := createRandomVerifyEmail(t, createRandomUser(t), time.Now().Add(time.Hour))
+
+	_, err := testQueries.UpdateVerifyEmail(context.Background(), UpdateVerifyEmailParams{
+		ID:         verifyEmail.ID,
+		SecretCode: util.RandomString(32),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+}
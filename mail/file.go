@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileSender appends every email to a file instead of delivering it,
+// a stand-in for an SMTP server during local development
+type FileSender struct {
+	mu          sync.Mutex
+	path        string
+	fromAddress string
+}
+
+func NewFileSender(path string, fromAddress string) *FileSender {
+	return &FileSender{
+		path:        path,
+		fromAddress: fromAddress,
+	}
+}
+
+func (sender *FileSender) SendEmail(subject string, content string, to []string) error {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	file, err := os.OpenFile(sender.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail file: %w", err)
+	}
+	defer file.Close()
+
+	msg := buildMessage(sender.fromAddress, subject, content, to)
+	if _, err := file.Write(append(msg, "\r\n"...)); err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package mail
+
+import "sync"
+
+// Message is an email kept by MemorySender
+type Message struct {
+	Subject string
+	Content string
+	To      []string
+}
+
+// MemorySender keeps the emails instead of delivering them, tests read them back with Messages
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (sender *MemorySender) SendEmail(subject string, content string, to []string) error {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	sender.messages = append(sender.messages, Message{
+		Subject: subject,
+		Content: content,
+		To:      append([]string(nil), to...),
+	})
+
+	return nil
+}
+
+// Messages returns the emails sent so far, oldest first
+func (sender *MemorySender) Messages() []Message {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	return append([]Message(nil), sender.messages...)
+}
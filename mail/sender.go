@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"fmt"
+
+	"github.com/CM-IV/mef-api/util"
+)
+
+// Sender delivers emails to users
+type Sender interface {
+	SendEmail(subject string, content string, to []string) error
+}
+
+// Kinds of sender that can be picked with EMAIL_SENDER
+const (
+	SMTPSenderKind   = "smtp"
+	FileSenderKind   = "file"
+	MemorySenderKind = "memory"
+)
+
+// NewSender creates the sender configured by EMAIL_SENDER. It has to be set,
+// an unset variable must not quietly keep every email in memory.
+func NewSender(config util.Config) (Sender, error) {
+	switch config.EmailSender {
+	case SMTPSenderKind:
+		return NewSMTPSender(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.EmailFromAddress), nil
+	case FileSenderKind:
+		return NewFileSender(config.EmailFilePath, config.EmailFromAddress), nil
+	case MemorySenderKind:
+		return NewMemorySender(), nil
+	case "":
+		return nil, fmt.Errorf("EMAIL_SENDER must be one of %s, %s or %s", SMTPSenderKind, FileSenderKind, MemorySenderKind)
+	}
+
+	return nil, fmt.Errorf("unknown email sender %q", config.EmailSender)
+}
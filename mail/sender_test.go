@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSender(t *testing.T) {
+	sender, err := NewSender(util.Config{EmailSender: MemorySenderKind})
+	require.NoError(t, err)
+	require.IsType(t, &MemorySender{}, sender)
+
+	_, err = NewSender(util.Config{})
+	require.Error(t, err)
+
+	sender, err = NewSender(util.Config{EmailSender: FileSenderKind, EmailFilePath: "mail.log"})
+	require.NoError(t, err)
+	require.IsType(t, &FileSender{}, sender)
+
+	sender, err = NewSender(util.Config{EmailSender: SMTPSenderKind, SMTPHost: "localhost", SMTPPort: 25})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPSender{}, sender)
+
+	_, err = NewSender(util.Config{EmailSender: "pigeon"})
+	require.Error(t, err)
+}
+
+func TestMemorySender(t *testing.T) {
+	sender := NewMemorySender()
+
+	err := sender.SendEmail("subject", "content", []string{"user@email.com"})
+	require.NoError(t, err)
+
+	messages := sender.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, "subject", messages[0].Subject)
+	require.Equal(t, "content", messages[0].Content)
+	require.Equal(t, []string{"user@email.com"}, messages[0].To)
+}
+
+func TestFileSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	sender := NewFileSender(path, "no-reply@localhost")
+
+	err := sender.SendEmail("first", "hello", []string{"user@email.com"})
+	require.NoError(t, err)
+
+	err = sender.SendEmail("second", "world", []string{"user@email.com"})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	content := string(data)
+	require.Equal(t, 2, strings.Count(content, "From: no-reply@localhost\r\n"))
+	require.Contains(t, content, "Subject: first\r\n")
+	require.Contains(t, content, "Subject: second\r\n")
+	require.Contains(t, content, "\r\n\r\nworld\r\n")
+}
+
+func TestBuildMessage(t *testing.T) {
+	msg := string(buildMessage("no-reply@localhost", "subject", "line one\nline two", []string{"a@email.com", "b@email.com"}))
+
+	require.True(t, strings.HasPrefix(msg, "From: no-reply@localhost\r\nTo: a@email.com, b@email.com\r\nSubject: subject\r\n"))
+	require.True(t, strings.HasSuffix(msg, "\r\n\r\nline one\r\nline two\r\n"))
+}
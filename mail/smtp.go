@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPSender sends emails through an SMTP server
+type SMTPSender struct {
+	addr        string
+	auth        smtp.Auth
+	fromAddress string
+}
+
+// NewSMTPSender creates a sender for the server at host:port, authentication is skipped without a username
+func NewSMTPSender(host string, port int, username string, password string, fromAddress string) *SMTPSender {
+	sender := &SMTPSender{
+		addr:        net.JoinHostPort(host, strconv.Itoa(port)),
+		fromAddress: fromAddress,
+	}
+
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return sender
+}
+
+func (sender *SMTPSender) SendEmail(subject string, content string, to []string) error {
+	msg := buildMessage(sender.fromAddress, subject, content, to)
+
+	err := smtp.SendMail(sender.addr, sender.auth, sender.fromAddress, to, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// buildMessage formats a plain text RFC 5322 message
+func buildMessage(from string, subject string, content string, to []string) []byte {
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(content, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	return msg.Bytes()
+}
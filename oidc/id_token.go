@@ -0,0 +1,334 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Signing algorithms accepted for ID tokens
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Clocks of the provider and the server may differ by this much
+const clockSkew = time.Minute
+
+// Keys are fetched again for an unknown key ID at most this often
+const minKeyRefreshInterval = time.Minute
+
+const minRSAKeySize = 2048
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+var jwtEncoding = base64.RawURLEncoding.Strict()
+
+// Claims of an ID token that identify the user
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Nonce             string   `json:"nonce"`
+	IssuedAt          int64    `json:"iat"`
+	ExpiresAt         int64    `json:"exp"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// The aud claim is either a single string or a list of them
+type audience []string
+
+func (aud *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*aud = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*aud = list
+	return nil
+}
+
+func (aud audience) contains(clientID string) bool {
+	for _, a := range aud {
+		if a == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// A key of the JWK set of the provider (RFC 7517), the algorithm follows from its type
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type publicKey struct {
+	id        string
+	algorithm string
+	key       crypto.PublicKey
+}
+
+// VerifyIDToken checks the signature and claims of an ID token returned by Exchange.
+// The nonce must be the one sent to the authorization endpoint.
+func (provider *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*Claims, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	header := jwtHeader{}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	key, err := provider.verificationKey(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if !verifySignature(key, parts[0]+"."+parts[1], signature) {
+		return nil, ErrInvalidIDToken
+	}
+
+	claims := &Claims{}
+	if err := decodeJWTSegment(parts[1], claims); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	if err := provider.validate(claims, nonce, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (provider *Provider) validate(claims *Claims, nonce string, now time.Time) error {
+	switch {
+	case claims.Issuer != provider.config.Issuer:
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	case !claims.Audience.contains(provider.config.ClientID):
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	case claims.AuthorizedParty != "" && claims.AuthorizedParty != provider.config.ClientID:
+		return fmt.Errorf("%w: issued to another client", ErrInvalidIDToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty == "":
+		return fmt.Errorf("%w: authorized party missing", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return fmt.Errorf("%w: subject missing", ErrInvalidIDToken)
+	case nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	case claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)):
+		return fmt.Errorf("%w: token has expired", ErrInvalidIDToken)
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(clockSkew)):
+		return fmt.Errorf("%w: token issued in the future", ErrInvalidIDToken)
+	}
+	return nil
+}
+
+// verificationKey finds the key of the kid header, the key must be meant for the alg header.
+// Tokens without a key ID are accepted when the provider has a single key for the algorithm.
+// The keys are fetched without holding the lock, tokens of known keys are verified meanwhile.
+func (provider *Provider) verificationKey(ctx context.Context, header jwtHeader) (publicKey, error) {
+	md, err := provider.discover(ctx)
+	if err != nil {
+		return publicKey{}, err
+	}
+
+	provider.mu.Lock()
+	key, found := findKey(provider.keys, header)
+	refresh := !found && time.Since(provider.keysFetchedAt) >= minKeyRefreshInterval
+	provider.mu.Unlock()
+
+	if refresh {
+		set := jsonWebKeySet{}
+		if err := provider.getJSON(ctx, md.JWKSURI, &set); err != nil {
+			return publicKey{}, fmt.Errorf("cannot fetch keys of provider %s: %w", provider.config.Name, err)
+		}
+
+		keys := parseKeySet(set)
+
+		provider.mu.Lock()
+		provider.keys = keys
+		provider.keysFetchedAt = time.Now()
+		provider.mu.Unlock()
+
+		key, found = findKey(keys, header)
+	}
+
+	if !found {
+		return publicKey{}, fmt.Errorf("%w: unknown signing key", ErrInvalidIDToken)
+	}
+
+	return key, nil
+}
+
+func findKey(keys []publicKey, header jwtHeader) (publicKey, bool) {
+	matches := []publicKey{}
+	for _, key := range keys {
+		if key.algorithm != header.Algorithm {
+			continue
+		}
+		if header.KeyID == "" || key.id == header.KeyID {
+			matches = append(matches, key)
+		}
+	}
+
+	if len(matches) != 1 {
+		return publicKey{}, false
+	}
+	return matches[0], true
+}
+
+// parseKeySet keeps the signing keys of supported types, the others are skipped
+func parseKeySet(set jsonWebKeySet) []publicKey {
+	keys := []publicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := parseKey(jwk)
+		if err != nil {
+			continue
+		}
+		if jwk.Algorithm != "" && jwk.Algorithm != key.algorithm {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func parseKey(jwk jsonWebKey) (publicKey, error) {
+	key := publicKey{id: jwk.KeyID}
+
+	switch {
+	case jwk.KeyType == "RSA":
+		n, err := jwtEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return key, err
+		}
+		e, err := jwtEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return key, errors.New("invalid RSA exponent")
+		}
+
+		rsaKey := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if rsaKey.N.BitLen() < minRSAKeySize {
+			return key, errors.New("RSA key is too small")
+		}
+
+		key.algorithm = AlgorithmRS256
+		key.key = rsaKey
+
+	case jwk.KeyType == "EC" && jwk.Curve == "P-256":
+		x, err := jwtEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return key, err
+		}
+		y, err := jwtEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return key, err
+		}
+
+		ecKey := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !ecKey.Curve.IsOnCurve(ecKey.X, ecKey.Y) {
+			return key, errors.New("EC point is not on the curve")
+		}
+
+		key.algorithm = AlgorithmES256
+		key.key = ecKey
+
+	case jwk.KeyType == "OKP" && jwk.Curve == "Ed25519":
+		x, err := jwtEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return key, errors.New("invalid Ed25519 key")
+		}
+
+		key.algorithm = AlgorithmEdDSA
+		key.key = ed25519.PublicKey(x)
+
+	default:
+		return key, fmt.Errorf("unsupported key type %s", jwk.KeyType)
+	}
+
+	return key, nil
+}
+
+func verifySignature(key publicKey, signingInput string, signature []byte) bool {
+	switch pub := key.key.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, []byte(signingInput), signature)
+	}
+	return false
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := jwtEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
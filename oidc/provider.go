@@ -0,0 +1,264 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scopes asked for at the authorization endpoint, enough for the email and name of the user
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// Responses of providers are never expected to be larger than this
+const maxResponseSize = 1 << 20
+
+var validProviderName = regexp.MustCompile(`^[a-z0-9]+$`).MatchString
+
+// Config of a provider, the client is registered with it beforehand
+type Config struct {
+	// Name of the provider in the login URLs, e.g. gitlab
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// New identities may be linked to the local user with the same verified email address.
+	// Only for providers that never mark addresses as verified without checking them.
+	LinkByEmail bool
+}
+
+// ParseConfigs reads the providers of OIDC_PROVIDERS, a comma separated list of
+// name|issuer|client_id|client_secret entries
+func ParseConfigs(spec string) ([]Config, error) {
+	configs := []Config{}
+	names := map[string]bool{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, "|")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid OIDC provider %q, expected name|issuer|client_id|client_secret", entry)
+		}
+
+		config := Config{
+			Name:         fields[0],
+			Issuer:       fields[1],
+			ClientID:     fields[2],
+			ClientSecret: fields[3],
+		}
+
+		if !validProviderName(config.Name) {
+			return nil, fmt.Errorf("invalid OIDC provider name %q, only lowercase letters and digits are allowed", config.Name)
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("duplicate OIDC provider %q", config.Name)
+		}
+		if issuer, err := url.Parse(config.Issuer); err != nil || issuer.Host == "" || (issuer.Scheme != "https" && issuer.Scheme != "http") {
+			return nil, fmt.Errorf("invalid issuer %q of OIDC provider %q", config.Issuer, config.Name)
+		}
+		if config.ClientID == "" {
+			return nil, fmt.Errorf("OIDC provider %q has no client ID", config.Name)
+		}
+
+		names[config.Name] = true
+		configs = append(configs, config)
+	}
+
+	return configs, nil
+}
+
+// Provider metadata from the discovery document, only the fields the login needs
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider logs users in with the authorization code flow of an OpenID provider.
+// The discovery document and the signing keys are fetched on first use and
+// cached, the keys are fetched again when a token is signed by an unknown key.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu            sync.Mutex
+	metadata      *metadata
+	keys          []publicKey
+	keysFetchedAt time.Time
+}
+
+// NewProvider creates a provider, nothing is fetched until the first login
+func NewProvider(config Config, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Provider{config: config, client: client}
+}
+
+// Name of the provider in the login URLs
+func (provider *Provider) Name() string {
+	return provider.config.Name
+}
+
+// LinkByEmail tells if identities of the provider may be linked to local users by email address
+func (provider *Provider) LinkByEmail() bool {
+	return provider.config.LinkByEmail
+}
+
+// AuthCodeURL returns the URL the user is sent to for logging in at the provider.
+// The state and nonce come back in the callback and the ID token, the code
+// challenge is derived from the verifier later sent to the token endpoint.
+func (provider *Provider) AuthCodeURL(ctx context.Context, redirectURL string, state string, nonce string, codeChallenge string) (string, error) {
+	md, err := provider.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(md.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.config.ClientID)
+	query.Set("redirect_uri", redirectURL)
+	query.Set("scope", strings.Join(defaultScopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades the code of the callback for the tokens of the user and returns the raw ID token.
+// The client authenticates with HTTP basic auth, the default of OpenID Connect.
+func (provider *Provider) Exchange(ctx context.Context, redirectURL string, code string, codeVerifier string) (string, error) {
+	md, err := provider.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(provider.config.ClientID), url.QueryEscape(provider.config.ClientSecret))
+
+	rsp, err := provider.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot reach token endpoint: %w", err)
+	}
+	defer rsp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(rsp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+
+	if rsp.StatusCode != http.StatusOK || token.Error != "" {
+		if token.Error == "" {
+			return "", fmt.Errorf("token endpoint returned status %d", rsp.StatusCode)
+		}
+		return "", fmt.Errorf("token endpoint returned %s: %s", token.Error, token.ErrorDescription)
+	}
+
+	if token.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+
+	return token.IDToken, nil
+}
+
+// discover fetches the discovery document once, its issuer must be the configured one.
+// The lock is not held during the request, a slow provider must not hold up the logins
+// that already have what they need.
+func (provider *Provider) discover(ctx context.Context) (*metadata, error) {
+	provider.mu.Lock()
+	cached := provider.metadata
+	provider.mu.Unlock()
+
+	if cached != nil {
+		return cached, nil
+	}
+
+	wellKnown := strings.TrimSuffix(provider.config.Issuer, "/") + "/.well-known/openid-configuration"
+
+	md := &metadata{}
+	if err := provider.getJSON(ctx, wellKnown, md); err != nil {
+		return nil, fmt.Errorf("cannot discover provider %s: %w", provider.config.Name, err)
+	}
+
+	if md.Issuer != provider.config.Issuer {
+		return nil, fmt.Errorf("provider %s has issuer %q, expected %q", provider.config.Name, md.Issuer, provider.config.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of provider %s is missing endpoints", provider.config.Name)
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.metadata == nil {
+		provider.metadata = md
+	}
+	return provider.metadata, nil
+}
+
+func (provider *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	rsp, err := provider.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, rsp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(rsp.Body, maxResponseSize)).Decode(v)
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a code verifier (RFC 7636)
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
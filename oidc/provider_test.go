@@ -0,0 +1,317 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/oidc/oidctest"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURL = "http://localhost:8080/api/auth/oidc/fake/callback"
+
+func newTestProvider(t *testing.T, fake *oidctest.Provider) *Provider {
+	return NewProvider(Config{
+		Name:         "fake",
+		Issuer:       fake.Issuer(),
+		ClientID:     fake.ClientID,
+		ClientSecret: fake.ClientSecret,
+	}, nil)
+}
+
+// authorize follows the authorization URL and returns the query of the callback
+func authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	rsp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusFound, rsp.StatusCode)
+
+	location, err := rsp.Location()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(location.String(), testRedirectURL))
+
+	return location.Query()
+}
+
+func TestParseConfigs(t *testing.T) {
+	configs, err := ParseConfigs("gitlab|https://gitlab.com|id|secret, google|https://accounts.google.com|id2|secret2")
+	require.NoError(t, err)
+	require.Equal(t, []Config{
+		{Name: "gitlab", Issuer: "https://gitlab.com", ClientID: "id", ClientSecret: "secret"},
+		{Name: "google", Issuer: "https://accounts.google.com", ClientID: "id2", ClientSecret: "secret2"},
+	}, configs)
+
+	configs, err = ParseConfigs("")
+	require.NoError(t, err)
+	require.Empty(t, configs)
+
+	for _, spec := range []string{
+		"gitlab|https://gitlab.com|id",
+		"Git-Lab|https://gitlab.com|id|secret",
+		"gitlab|gitlab.com|id|secret",
+		"gitlab|https://gitlab.com||secret",
+		"gitlab|https://gitlab.com|id|secret,gitlab|https://gitlab.example|id|secret",
+	} {
+		_, err := ParseConfigs(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestProviderLogin(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newTestProvider(t, fake)
+	ctx := context.Background()
+
+	verifier := "verifier-" + strings.Repeat("x", 40)
+
+	authURL, err := provider.AuthCodeURL(ctx, testRedirectURL, "state-1", "nonce-1", CodeChallenge(verifier))
+	require.NoError(t, err)
+
+	query, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "openid email profile", query.Query().Get("scope"))
+	require.Equal(t, "S256", query.Query().Get("code_challenge_method"))
+
+	callback := authorize(t, authURL)
+	require.Equal(t, "state-1", callback.Get("state"))
+
+	idToken, err := provider.Exchange(ctx, testRedirectURL, callback.Get("code"), verifier)
+	require.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(ctx, idToken, "nonce-1")
+	require.NoError(t, err)
+	require.Equal(t, fake.Issuer(), claims.Issuer)
+	require.Equal(t, "248289761001", claims.Subject)
+	require.Equal(t, "jane@example.com", claims.Email)
+	require.True(t, claims.EmailVerified)
+	require.Equal(t, "jane", claims.PreferredUsername)
+
+	// The code was used up by the first exchange
+	_, err = provider.Exchange(ctx, testRedirectURL, callback.Get("code"), verifier)
+	require.Error(t, err)
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newTestProvider(t, fake)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, testRedirectURL, "state", "nonce", CodeChallenge("verifier-one"))
+	require.NoError(t, err)
+
+	callback := authorize(t, authURL)
+
+	_, err = provider.Exchange(ctx, testRedirectURL, callback.Get("code"), "verifier-two")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid_grant")
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+
+	provider := NewProvider(Config{
+		Name:     "fake",
+		Issuer:   fake.Issuer() + "/other",
+		ClientID: fake.ClientID,
+	}, nil)
+
+	_, err := provider.AuthCodeURL(context.Background(), testRedirectURL, "state", "nonce", "challenge")
+	require.Error(t, err)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	provider := newTestProvider(t, fake)
+	user := oidctest.User{Subject: "42", Email: "jane@example.com", EmailVerified: true}
+
+	claims := func(change func(claims map[string]interface{})) string {
+		c := fake.Claims(user, "nonce")
+		change(c)
+		return fake.SignIDToken(c)
+	}
+
+	valid := fake.SignIDToken(fake.Claims(user, "nonce"))
+	parts := strings.Split(valid, ".")
+
+	testCases := []struct {
+		name  string
+		token string
+		nonce string
+		ok    bool
+	}{
+		{
+			name:  "OK",
+			token: valid,
+			nonce: "nonce",
+			ok:    true,
+		},
+		{
+			name:  "WrongNonce",
+			token: valid,
+			nonce: "other",
+		},
+		{
+			name:  "NoNonce",
+			token: claims(func(c map[string]interface{}) { c["nonce"] = "" }),
+		},
+		{
+			name:  "Expired",
+			token: claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() }),
+			nonce: "nonce",
+		},
+		{
+			name:  "ExpiredWithinSkew",
+			token: claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-10 * time.Second).Unix() }),
+			nonce: "nonce",
+			ok:    true,
+		},
+		{
+			name:  "IssuedInTheFuture",
+			token: claims(func(c map[string]interface{}) { c["iat"] = time.Now().Add(time.Hour).Unix() }),
+			nonce: "nonce",
+		},
+		{
+			name:  "OtherIssuer",
+			token: claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example" }),
+			nonce: "nonce",
+		},
+		{
+			name:  "OtherAudience",
+			token: claims(func(c map[string]interface{}) { c["aud"] = "other-client" }),
+			nonce: "nonce",
+		},
+		{
+			name:  "AudienceList",
+			token: claims(func(c map[string]interface{}) { c["aud"] = []string{"other-client", fake.ClientID}; c["azp"] = fake.ClientID }),
+			nonce: "nonce",
+			ok:    true,
+		},
+		{
+			name:  "AudienceListWithoutAuthorizedParty",
+			token: claims(func(c map[string]interface{}) { c["aud"] = []string{"other-client", fake.ClientID} }),
+			nonce: "nonce",
+		},
+		{
+			name:  "NoSubject",
+			token: claims(func(c map[string]interface{}) { c["sub"] = "" }),
+			nonce: "nonce",
+		},
+		{
+			name:  "AlgorithmNone",
+			token: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"test-key"}`)) + "." + parts[1] + ".",
+			nonce: "nonce",
+		},
+		{
+			name:  "UnknownKey",
+			token: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"other-key"}`)) + "." + parts[1] + "." + parts[2],
+			nonce: "nonce",
+		},
+		{
+			name:  "TamperedClaims",
+			token: parts[0] + "." + strings.Split(claims(func(c map[string]interface{}) { c["sub"] = "43" }), ".")[1] + "." + parts[2],
+			nonce: "nonce",
+		},
+		{
+			name:  "Malformed",
+			token: "a.b",
+			nonce: "nonce",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := provider.VerifyIDToken(context.Background(), tc.token, tc.nonce)
+			if tc.ok {
+				require.NoError(t, err)
+				require.Equal(t, user.Subject, claims.Subject)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidIDToken)
+			require.Nil(t, claims)
+		})
+	}
+}
+
+// blockingTransport holds every request for the keys after the first one until released
+type blockingTransport struct {
+	mu       sync.Mutex
+	calls    int
+	started  chan struct{}
+	released chan struct{}
+}
+
+func (transport *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/jwks" {
+		transport.mu.Lock()
+		transport.calls++
+		blocked := transport.calls > 1
+		transport.mu.Unlock()
+
+		if blocked {
+			close(transport.started)
+			<-transport.released
+		}
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestVerifyIDTokenDuringKeyRefresh(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	transport := &blockingTransport{started: make(chan struct{}), released: make(chan struct{})}
+
+	provider := NewProvider(Config{
+		Name:     "fake",
+		Issuer:   fake.Issuer(),
+		ClientID: fake.ClientID,
+	}, &http.Client{Transport: transport})
+
+	user := oidctest.User{Subject: "42", Email: "jane@example.com", EmailVerified: true}
+	valid := fake.SignIDToken(fake.Claims(user, "nonce"))
+	parts := strings.Split(valid, ".")
+	unknownKey := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"other-key"}`)) + "." + parts[1] + "." + parts[2]
+
+	_, err := provider.VerifyIDToken(context.Background(), valid, "nonce")
+	require.NoError(t, err)
+
+	// The unknown key makes the provider fetch the keys again, the provider does not answer
+	provider.mu.Lock()
+	provider.keysFetchedAt = time.Now().Add(-minKeyRefreshInterval)
+	provider.mu.Unlock()
+
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := provider.VerifyIDToken(context.Background(), unknownKey, "nonce")
+		refreshed <- err
+	}()
+	<-transport.started
+
+	verified := make(chan error, 1)
+	go func() {
+		_, err := provider.VerifyIDToken(context.Background(), valid, "nonce")
+		verified <- err
+	}()
+
+	select {
+	case err := <-verified:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("verification waited for the key refresh")
+	}
+
+	close(transport.released)
+	require.ErrorIs(t, <-refreshed, ErrInvalidIDToken)
+}
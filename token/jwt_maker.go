@@ -0,0 +1,240 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signing algorithms of the JWTs, the names are the alg header values
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const minJWTSecretKeySize = 32
+
+var jwtEncoding = base64.RawURLEncoding.Strict()
+
+// JWTMaker issues JWTs for the services that do not understand PASETO.
+// A maker signs and verifies with a single algorithm, the alg header of a
+// token is only compared against it, so tokens with alg none or with an
+// algorithm picked by the sender are rejected.
+type JWTMaker struct {
+	algorithm string
+	secretKey []byte
+	keys      *KeyRing
+	options   Options
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// Registered claims carry the payload, the role and token generation are private claims.
+// Scopes are space separated like the OAuth scope claim.
+type jwtClaims struct {
+	ID         string `json:"jti"`
+	Issuer     string `json:"iss,omitempty"`
+	Audience   string `json:"aud,omitempty"`
+	Subject    string `json:"sub"`
+	Role       string `json:"role"`
+	Generation int32  `json:"gen"`
+	Scope      string `json:"scope,omitempty"`
+	IssuedAt   int64  `json:"iat"`
+	NotBefore  int64  `json:"nbf,omitempty"`
+	ExpiredAt  int64  `json:"exp"`
+}
+
+// NewJWTMaker creates a maker of HS256 tokens
+func NewJWTMaker(secretKey string, options Options) (Maker, error) {
+	if len(secretKey) < minJWTSecretKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minJWTSecretKeySize)
+	}
+
+	maker := &JWTMaker{
+		algorithm: AlgorithmHS256,
+		secretKey: []byte(secretKey),
+		options:   options,
+	}
+
+	return maker, nil
+}
+
+// NewJWTPublicMaker creates a maker of EdDSA tokens, the key ID goes in the kid header
+func NewJWTPublicMaker(keys *KeyRing, options Options) (Maker, error) {
+	if keys == nil {
+		return nil, errors.New("a key ring is required to sign tokens")
+	}
+
+	maker := &JWTMaker{
+		algorithm: AlgorithmEdDSA,
+		keys:      keys,
+		options:   options,
+	}
+
+	return maker, nil
+}
+
+//Creates new token for a specific username, role, token generation, scopes & duration
+func (maker *JWTMaker) CreateToken(username string, role string, generation int32, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := maker.options.newPayload(username, role, generation, scopes, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// JWT times only have a precision of seconds
+	payload.IssuedAt = time.Unix(payload.IssuedAt.Unix(), 0)
+	payload.NotBefore = payload.IssuedAt
+	payload.ExpiredAt = time.Unix(payload.ExpiredAt.Unix(), 0)
+
+	header := jwtHeader{Algorithm: maker.algorithm, Type: "JWT"}
+	if maker.keys != nil {
+		header.KeyID = maker.keys.signingID
+	}
+
+	claims := jwtClaims{
+		ID:         payload.ID.String(),
+		Issuer:     payload.Issuer,
+		Audience:   payload.Audience,
+		Subject:    payload.UserName,
+		Role:       payload.Role,
+		Generation: payload.Generation,
+		Scope:      strings.Join(payload.Scopes, " "),
+		IssuedAt:   payload.IssuedAt.Unix(),
+		NotBefore:  payload.NotBefore.Unix(),
+		ExpiredAt:  payload.ExpiredAt.Unix(),
+	}
+
+	encodedHeader, err := encodeJWTSegment(header)
+	if err != nil {
+		return "", nil, err
+	}
+
+	encodedClaims, err := encodeJWTSegment(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	signingInput := encodedHeader + "." + encodedClaims
+
+	var signature []byte
+	switch maker.algorithm {
+	case AlgorithmHS256:
+		signature = maker.mac(signingInput)
+	case AlgorithmEdDSA:
+		signature = ed25519.Sign(maker.keys.signingKey, []byte(signingInput))
+	}
+
+	return signingInput + "." + jwtEncoding.EncodeToString(signature), payload, nil
+}
+
+//Checks if token is valid or not
+func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	header := jwtHeader{}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if header.Algorithm != maker.algorithm || (header.Type != "" && header.Type != "JWT") {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+
+	switch maker.algorithm {
+	case AlgorithmHS256:
+		if !hmac.Equal(signature, maker.mac(signingInput)) {
+			return nil, ErrInvalidToken
+		}
+	case AlgorithmEdDSA:
+		publicKey, ok := maker.keys.PublicKey(header.KeyID)
+		if !ok || !ed25519.Verify(publicKey, []byte(signingInput), signature) {
+			return nil, ErrInvalidToken
+		}
+	default:
+		return nil, ErrInvalidToken
+	}
+
+	claims := jwtClaims{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil || claims.Subject == "" || claims.IssuedAt == 0 || claims.ExpiredAt == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{
+		ID:         tokenID,
+		Issuer:     claims.Issuer,
+		Audience:   claims.Audience,
+		UserName:   claims.Subject,
+		Role:       claims.Role,
+		Generation: claims.Generation,
+		Scopes:     strings.Fields(claims.Scope),
+		IssuedAt:   time.Unix(claims.IssuedAt, 0),
+		ExpiredAt:  time.Unix(claims.ExpiredAt, 0),
+	}
+	if claims.NotBefore != 0 {
+		payload.NotBefore = time.Unix(claims.NotBefore, 0)
+	}
+
+	err = payload.Valid(maker.options)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+//Lists the keys that verify the tokens of the maker, none for HS256
+func (maker *JWTMaker) PublicKeys() []PublicKey {
+	if maker.keys == nil {
+		return nil
+	}
+	return maker.keys.PublicKeys()
+}
+
+func (maker *JWTMaker) mac(signingInput string) []byte {
+	mac := hmac.New(sha256.New, maker.secretKey)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func encodeJWTSegment(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return jwtEncoding.EncodeToString(data), nil
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := jwtEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
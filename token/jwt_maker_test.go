@@ -0,0 +1,189 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJWTMaker(t *testing.T) func(options Options) Maker {
+	secretKey := util.RandomString(32)
+
+	return func(options Options) Maker {
+		maker, err := NewJWTMaker(secretKey, options)
+		require.NoError(t, err)
+
+		return maker
+	}
+}
+
+func newTestJWTPublicMaker(t *testing.T) func(options Options) Maker {
+	ring := newTestKeyRing(t, "k1")
+
+	return func(options Options) Maker {
+		maker, err := NewJWTPublicMaker(ring, options)
+		require.NoError(t, err)
+
+		return maker
+	}
+}
+
+func TestJWTMaker(t *testing.T) {
+	testMaker(t, newTestJWTMaker)
+
+	_, err := NewJWTMaker(util.RandomString(31), Options{})
+	require.EqualError(t, err, "invalid key size: must be at least 32 characters")
+}
+
+func TestJWTPublicMaker(t *testing.T) {
+	testMaker(t, newTestJWTPublicMaker)
+}
+
+// Signs a token by hand so any header can be put in it
+func signJWT(header string, claims string, sign func(signingInput string) []byte) string {
+	signingInput := jwtEncoding.EncodeToString([]byte(header)) + "." + jwtEncoding.EncodeToString([]byte(claims))
+	return signingInput + "." + jwtEncoding.EncodeToString(sign(signingInput))
+}
+
+func TestJWTClaims(t *testing.T) {
+	maker := newTestJWTMaker(t)(Options{Issuer: "mef-api", Audience: "partner"})
+
+	token, payload, err := maker.CreateToken("user", util.UserRole, 3, []string{util.PostsWriteScope, util.AccountScope}, time.Minute)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	header := map[string]interface{}{}
+	require.NoError(t, decodeJWTSegment(parts[0], &header))
+	require.Equal(t, map[string]interface{}{"alg": "HS256", "typ": "JWT"}, header)
+
+	claims := map[string]interface{}{}
+	require.NoError(t, decodeJWTSegment(parts[1], &claims))
+	require.Equal(t, payload.ID.String(), claims["jti"])
+	require.Equal(t, "mef-api", claims["iss"])
+	require.Equal(t, "partner", claims["aud"])
+	require.Equal(t, "user", claims["sub"])
+	require.Equal(t, util.UserRole, claims["role"])
+	require.EqualValues(t, 3, claims["gen"])
+	require.Equal(t, "posts:write account", claims["scope"])
+	require.EqualValues(t, payload.IssuedAt.Unix(), claims["iat"])
+	require.EqualValues(t, payload.IssuedAt.Unix(), claims["nbf"])
+	require.EqualValues(t, payload.ExpiredAt.Unix(), claims["exp"])
+}
+
+func TestInvalidJWT(t *testing.T) {
+	secretKey := util.RandomString(32)
+	maker, err := NewJWTMaker(secretKey, Options{})
+	require.NoError(t, err)
+
+	ring := newTestKeyRing(t, "k1")
+	publicMaker, err := NewJWTPublicMaker(ring, Options{})
+	require.NoError(t, err)
+	publicKey, ok := ring.PublicKey("k1")
+	require.True(t, ok)
+
+	token, _, err := maker.CreateToken("user", util.UserRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+
+	claims := fmt.Sprintf(
+		`{"jti":"5b8a6c1e-3b57-4a5c-9d38-2a5f0f6c1e11","sub":"admin","role":"admin","gen":0,"iat":%d,"exp":%d}`,
+		time.Now().Unix(),
+		time.Now().Add(time.Hour).Unix(),
+	)
+	hs256 := func(key []byte) func(string) []byte {
+		return func(signingInput string) []byte {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(signingInput))
+			return mac.Sum(nil)
+		}
+	}
+	noSignature := func(string) []byte {
+		return nil
+	}
+
+	testCases := []struct {
+		name  string
+		maker Maker
+		token string
+	}{
+		{
+			name:  "AlgorithmNone",
+			maker: maker,
+			token: signJWT(`{"alg":"none","typ":"JWT"}`, claims, noSignature),
+		},
+		{
+			name:  "AlgorithmNoneUppercase",
+			maker: maker,
+			token: signJWT(`{"alg":"NONE"}`, claims, noSignature),
+		},
+		{
+			// The public key is no secret, an HS256 token keyed with it must not pass as EdDSA
+			name:  "AlgorithmConfusion",
+			maker: publicMaker,
+			token: signJWT(`{"alg":"HS256","typ":"JWT","kid":"k1"}`, claims, hs256(publicKey)),
+		},
+		{
+			name:  "EdDSAForHS256Maker",
+			maker: maker,
+			token: signJWT(`{"alg":"EdDSA","typ":"JWT","kid":"k1"}`, claims, func(signingInput string) []byte {
+				return ed25519.Sign(ring.signingKey, []byte(signingInput))
+			}),
+		},
+		{
+			name:  "UnknownKeyID",
+			maker: publicMaker,
+			token: signJWT(`{"alg":"EdDSA","typ":"JWT","kid":"k2"}`, claims, func(signingInput string) []byte {
+				return ed25519.Sign(ring.signingKey, []byte(signingInput))
+			}),
+		},
+		{
+			name:  "WrongType",
+			maker: maker,
+			token: signJWT(`{"alg":"HS256","typ":"JWE"}`, claims, hs256([]byte(secretKey))),
+		},
+		{
+			name:  "MissingClaims",
+			maker: maker,
+			token: signJWT(`{"alg":"HS256","typ":"JWT"}`, `{"sub":"admin"}`, hs256([]byte(secretKey))),
+		},
+		{
+			name:  "TamperedClaims",
+			maker: maker,
+			token: parts[0] + "." + jwtEncoding.EncodeToString([]byte(claims)) + "." + parts[2],
+		},
+		{
+			name:  "PaddedSignature",
+			maker: maker,
+			token: token + "=",
+		},
+		{
+			name:  "ExtraSegment",
+			maker: maker,
+			token: token + ".",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := tc.maker.VerifyToken(tc.token)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+
+	// The hand signed claims pass once signed with the right key and algorithm
+	payload, err := maker.VerifyToken(signJWT(`{"alg":"HS256","typ":"JWT"}`, claims, hs256([]byte(secretKey))))
+	require.NoError(t, err)
+	require.Equal(t, "admin", payload.UserName)
+}
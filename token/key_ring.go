@@ -0,0 +1,154 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PublicKey is an Ed25519 key that verifies the tokens carrying its ID in their footer
+type PublicKey struct {
+	ID  string
+	Key ed25519.PublicKey
+}
+
+// KeyRing holds the private key that signs new tokens and the public keys that verify them.
+// Keys are rotated by signing with a new key while the previous ones stay in the ring
+// until the tokens they signed have expired.
+type KeyRing struct {
+	signingID  string
+	signingKey ed25519.PrivateKey
+	publicKeys []PublicKey
+}
+
+// NewKeyRing creates a key ring that signs with the given private key
+func NewKeyRing(id string, privateKey ed25519.PrivateKey) (*KeyRing, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: must be %d bytes", ed25519.PrivateKeySize)
+	}
+
+	ring := &KeyRing{
+		signingID:  id,
+		signingKey: privateKey,
+	}
+
+	err := ring.AddPublicKey(id, privateKey.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return ring, nil
+}
+
+// AddPublicKey adds a key that only verifies tokens, e.g. a key that signed tokens before a rotation
+func (ring *KeyRing) AddPublicKey(id string, publicKey ed25519.PublicKey) error {
+	if err := validateKeyID(id); err != nil {
+		return err
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size for %q: must be %d bytes", id, ed25519.PublicKeySize)
+	}
+	if _, ok := ring.PublicKey(id); ok {
+		return fmt.Errorf("duplicate key ID %q", id)
+	}
+
+	ring.publicKeys = append(ring.publicKeys, PublicKey{ID: id, Key: publicKey})
+	return nil
+}
+
+// PublicKey returns the key that verifies the tokens with the given key ID
+func (ring *KeyRing) PublicKey(id string) (ed25519.PublicKey, bool) {
+	for _, key := range ring.publicKeys {
+		if key.ID == id {
+			return key.Key, true
+		}
+	}
+	return nil, false
+}
+
+// PublicKeys lists every key of the ring, the signing key first
+func (ring *KeyRing) PublicKeys() []PublicKey {
+	keys := make([]PublicKey, len(ring.publicKeys))
+	copy(keys, ring.publicKeys)
+	return keys
+}
+
+// ParseKeyRing reads keys written as comma separated id:base64 pairs.
+// Private keys are 32 byte Ed25519 seeds, the first one signs new tokens and the
+// others only verify. Public keys only verify, they are the retired keys whose
+// private half is no longer around.
+func ParseKeyRing(privateKeys string, publicKeys string) (*KeyRing, error) {
+	privates, err := parseKeyList(privateKeys, ed25519.SeedSize)
+	if err != nil {
+		return nil, fmt.Errorf("private keys: %w", err)
+	}
+	if len(privates) == 0 {
+		return nil, errors.New("private keys: at least one key is required to sign tokens")
+	}
+
+	ring, err := NewKeyRing(privates[0].id, ed25519.NewKeyFromSeed(privates[0].key))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, private := range privates[1:] {
+		publicKey := ed25519.NewKeyFromSeed(private.key).Public().(ed25519.PublicKey)
+		if err := ring.AddPublicKey(private.id, publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	publics, err := parseKeyList(publicKeys, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("public keys: %w", err)
+	}
+
+	for _, public := range publics {
+		if err := ring.AddPublicKey(public.id, public.key); err != nil {
+			return nil, err
+		}
+	}
+
+	return ring, nil
+}
+
+type encodedKey struct {
+	id  string
+	key []byte
+}
+
+func parseKeyList(list string, size int) ([]encodedKey, error) {
+	keys := []encodedKey{}
+	if strings.TrimSpace(list) == "" {
+		return keys, nil
+	}
+
+	for _, entry := range strings.Split(list, ",") {
+		fields := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid key %q, expected id:base64", entry)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", fields[0], err)
+		}
+		if len(key) != size {
+			return nil, fmt.Errorf("invalid key size for %q: must be %d bytes", fields[0], size)
+		}
+
+		keys = append(keys, encodedKey{id: fields[0], key: key})
+	}
+
+	return keys, nil
+}
+
+// Key IDs end up in the token footer and in the config lists
+func validateKeyID(id string) error {
+	if id == "" || strings.ContainsAny(id, ",: ") {
+		return fmt.Errorf("invalid key ID %q", id)
+	}
+	return nil
+}
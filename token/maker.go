@@ -2,11 +2,17 @@ package token
 
 import "time"
 
-//Interface for managing Paseto Tokens
+//Interface for managing Paseto and JWT Tokens
 type Maker interface {
-	//Creates new token for a specific username & duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	//Creates new token for a specific username, role, token generation, scopes & duration
+	CreateToken(username string, role string, generation int32, scopes []string, duration time.Duration) (string, *Payload, error)
 
 	//Checks if token is valid or not
 	VerifyToken(token string) (*Payload, error)
 }
+
+//Implemented by the makers whose tokens are verified with public keys
+type PublicKeyProvider interface {
+	//Lists the keys that verify the tokens of the maker
+	PublicKeys() []PublicKey
+}
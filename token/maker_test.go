@@ -0,0 +1,153 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+// Shared by the tests of every Maker implementation. A maker factory creates
+// a new key and returns a constructor of makers using that key with any options.
+type makerFactory func(t *testing.T) func(options Options) Maker
+
+func testMakerToken(t *testing.T, newMaker makerFactory) {
+	options := Options{Issuer: "mef-api", Audience: "mef-api"}
+	maker := newMaker(t)(options)
+
+	username := util.RandomOwner()
+	role := util.ModeratorRole
+	generation := int32(util.RandomInt(0, 10))
+	scopes := []string{util.PostsWriteScope, util.CommentsWriteScope}
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, generation, scopes, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	verified, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	require.NotZero(t, verified.ID)
+	require.Equal(t, payload.ID, verified.ID)
+	require.Equal(t, options.Issuer, verified.Issuer)
+	require.Equal(t, options.Audience, verified.Audience)
+	require.Equal(t, username, verified.UserName)
+	require.Equal(t, role, verified.Role)
+	require.Equal(t, generation, verified.Generation)
+	require.Equal(t, scopes, verified.Scopes)
+	require.WithinDuration(t, issuedAt, verified.IssuedAt, time.Second)
+	require.WithinDuration(t, issuedAt, verified.NotBefore, time.Second)
+	require.WithinDuration(t, expiredAt, verified.ExpiredAt, time.Second)
+	require.WithinDuration(t, payload.ExpiredAt, verified.ExpiredAt, time.Second)
+
+	require.True(t, verified.HasScope(util.PostsWriteScope))
+	require.False(t, verified.HasScope(util.UsersAdminScope))
+}
+
+func testMakerExpiredToken(t *testing.T, newMaker makerFactory) {
+	maker := newMaker(t)(Options{})
+
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.UserRole, 0, nil, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func testMakerClockSkew(t *testing.T, newMaker makerFactory) {
+	withKey := newMaker(t)
+	maker := withKey(Options{})
+	lenient := withKey(Options{ClockSkew: time.Minute})
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.UserRole, 0, nil, -2*time.Second)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+
+	payload, err = lenient.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotNil(t, payload)
+}
+
+func testMakerIssuerAndAudience(t *testing.T, newMaker makerFactory) {
+	withKey := newMaker(t)
+	maker := withKey(Options{Issuer: "mef-api", Audience: "mef-api"})
+
+	testCases := []struct {
+		name    string
+		options Options
+		err     error
+	}{
+		{name: "OtherIssuer", options: Options{Issuer: "other", Audience: "mef-api"}, err: ErrInvalidIssuer},
+		{name: "OtherAudience", options: Options{Issuer: "mef-api", Audience: "other"}, err: ErrInvalidAudience},
+		{name: "NoIssuer", options: Options{Audience: "mef-api"}, err: ErrInvalidIssuer},
+		{name: "NoAudience", options: Options{Issuer: "mef-api"}, err: ErrInvalidAudience},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			token, _, err := withKey(tc.options).CreateToken(util.RandomOwner(), util.UserRole, 0, nil, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token)
+			require.EqualError(t, err, tc.err.Error())
+			require.Nil(t, payload)
+		})
+	}
+
+	// A maker without expectations accepts tokens of any issuer and audience
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.UserRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := withKey(Options{}).VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, "mef-api", payload.Issuer)
+}
+
+func testMakerInvalidToken(t *testing.T, newMaker makerFactory) {
+	maker := newMaker(t)(Options{})
+	other := newMaker(t)(Options{})
+
+	token, _, err := other.CreateToken(util.RandomOwner(), util.AdminRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+
+	for _, invalid := range []string{token, "", util.RandomString(32), "a.b.c", "v2.local.abc"} {
+		payload, err := maker.VerifyToken(invalid)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+// Runs the shared tests against the makers of newMaker
+func testMaker(t *testing.T, newMaker makerFactory) {
+	t.Run("Token", func(t *testing.T) {
+		testMakerToken(t, newMaker)
+	})
+	t.Run("ExpiredToken", func(t *testing.T) {
+		testMakerExpiredToken(t, newMaker)
+	})
+	t.Run("ClockSkew", func(t *testing.T) {
+		testMakerClockSkew(t, newMaker)
+	})
+	t.Run("IssuerAndAudience", func(t *testing.T) {
+		testMakerIssuerAndAudience(t, newMaker)
+	})
+	t.Run("OtherKey", func(t *testing.T) {
+		testMakerInvalidToken(t, newMaker)
+	})
+}
@@ -11,9 +11,10 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte
+	options      Options
 }
 
-func NewPasetoMaker(symmetricKey string) (Maker, error) {
+func NewPasetoMaker(symmetricKey string, options Options) (Maker, error) {
 	if len(symmetricKey) != chacha20poly1305.KeySize {
 		return nil, fmt.Errorf("invalid key size: must be %d characters", chacha20poly1305.KeySize)
 	}
@@ -21,19 +22,21 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	maker := &PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: []byte(symmetricKey),
+		options:      options,
 	}
 
 	return maker, nil
 }
 
-//Creates new token for a specific username & duration
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+//Creates new token for a specific username, role, token generation, scopes & duration
+func (maker *PasetoMaker) CreateToken(username string, role string, generation int32, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := maker.options.newPayload(username, role, generation, scopes, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	return token, payload, err
 }
 
 //Checks if token is valid or not
@@ -45,7 +48,7 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	err = payload.Valid()
+	err = payload.Valid(maker.options)
 	if err != nil {
 		return nil, err
 	}
@@ -2,46 +2,22 @@ package token
 
 import (
 	"testing"
-	"time"
 
 	"github.com/CM-IV/mef-api/util"
 	"github.com/stretchr/testify/require"
 )
 
-func TestPasetoMaker(t *testing.T) {
-	maker, err := NewPasetoMaker(util.RandomString(32))
-	require.NoError(t, err)
-
-	username := util.RandomOwner()
-	duration := time.Minute
-
-	issuedAt := time.Now()
-	expiredAt := issuedAt.Add(duration)
-
-	token, err := maker.CreateToken(username, duration)
-	require.NoError(t, err)
-	require.NotEmpty(t, token)
+func newTestPasetoMaker(t *testing.T) func(options Options) Maker {
+	symmetricKey := util.RandomString(32)
 
-	payload, err := maker.VerifyToken(token)
-	require.NoError(t, err)
-	require.NotEmpty(t, token)
+	return func(options Options) Maker {
+		maker, err := NewPasetoMaker(symmetricKey, options)
+		require.NoError(t, err)
 
-	require.NotZero(t, payload.ID)
-	require.Equal(t, username, payload.UserName)
-	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
-	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+		return maker
+	}
 }
 
-func TestExpiredPasetoToken(t *testing.T) {
-	maker, err := NewPasetoMaker(util.RandomString(32))
-	require.NoError(t, err)
-
-	token, err := maker.CreateToken(util.RandomOwner(), -time.Minute)
-	require.NoError(t, err)
-	require.NotEmpty(t, token)
-
-	payload, err := maker.VerifyToken(token)
-	require.Error(t, err)
-	require.EqualError(t, err, ErrExpiredToken.Error())
-	require.Nil(t, payload)
+func TestPasetoMaker(t *testing.T) {
+	testMaker(t, newTestPasetoMaker)
 }
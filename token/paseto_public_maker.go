@@ -0,0 +1,81 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/o1egl/paseto"
+)
+
+// PasetoPublicMaker signs v2.public tokens with Ed25519, so services only need
+// the public keys to verify them. The payload is signed but not encrypted.
+type PasetoPublicMaker struct {
+	paseto  *paseto.V2
+	keys    *KeyRing
+	options Options
+}
+
+// Footer of the tokens, tells which key of the ring verifies the token
+type keyFooter struct {
+	KeyID string `json:"kid"`
+}
+
+func NewPasetoPublicMaker(keys *KeyRing, options Options) (Maker, error) {
+	if keys == nil {
+		return nil, errors.New("a key ring is required to sign tokens")
+	}
+
+	maker := &PasetoPublicMaker{
+		paseto:  paseto.NewV2(),
+		keys:    keys,
+		options: options,
+	}
+
+	return maker, nil
+}
+
+//Creates new token for a specific username, role, token generation, scopes & duration
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, generation int32, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := maker.options.newPayload(username, role, generation, scopes, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	footer := keyFooter{KeyID: maker.keys.signingID}
+	token, err := maker.paseto.Sign(maker.keys.signingKey, payload, footer)
+	return token, payload, err
+}
+
+//Checks if token is valid or not
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	footer := keyFooter{}
+
+	err := paseto.ParseFooter(token, &footer)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	publicKey, ok := maker.keys.PublicKey(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+
+	err = maker.paseto.Verify(token, publicKey, payload, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid(maker.options)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+//Lists the keys that verify the tokens of the maker
+func (maker *PasetoPublicMaker) PublicKeys() []PublicKey {
+	return maker.keys.PublicKeys()
+}
@@ -0,0 +1,170 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CM-IV/mef-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyRing(t *testing.T, id string) *KeyRing {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	ring, err := NewKeyRing(id, privateKey)
+	require.NoError(t, err)
+
+	return ring
+}
+
+func newTestPasetoPublicMaker(t *testing.T) func(options Options) Maker {
+	ring := newTestKeyRing(t, "k1")
+
+	return func(options Options) Maker {
+		maker, err := NewPasetoPublicMaker(ring, options)
+		require.NoError(t, err)
+
+		return maker
+	}
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	testMaker(t, newTestPasetoPublicMaker)
+
+	token, _, err := newTestPasetoPublicMaker(t)(Options{}).CreateToken(util.RandomOwner(), util.UserRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "v2.public."))
+}
+
+func TestPasetoPublicMakerKeyRotation(t *testing.T) {
+	oldRing := newTestKeyRing(t, "k1")
+	oldMaker, err := NewPasetoPublicMaker(oldRing, Options{})
+	require.NoError(t, err)
+
+	oldToken, _, err := oldMaker.CreateToken(util.RandomOwner(), util.UserRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+
+	// The new key signs while the old one still verifies
+	newRing := newTestKeyRing(t, "k2")
+	oldKey, ok := oldRing.PublicKey("k1")
+	require.True(t, ok)
+	require.NoError(t, newRing.AddPublicKey("k1", oldKey))
+
+	newMaker, err := NewPasetoPublicMaker(newRing, Options{})
+	require.NoError(t, err)
+
+	payload, err := newMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+	require.NotNil(t, payload)
+
+	newToken, _, err := newMaker.CreateToken(util.RandomOwner(), util.UserRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+
+	keys := newMaker.(PublicKeyProvider).PublicKeys()
+	require.Len(t, keys, 2)
+	require.Equal(t, "k2", keys[0].ID)
+	require.Equal(t, "k1", keys[1].ID)
+
+	// A ring without the key ID of the token rejects it
+	payload, err = oldMaker.VerifyToken(newToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	retiredMaker, err := NewPasetoPublicMaker(newTestKeyRing(t, "k3"), Options{})
+	require.NoError(t, err)
+
+	payload, err = retiredMaker.VerifyToken(oldToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidPasetoPublicToken(t *testing.T) {
+	ring := newTestKeyRing(t, "k1")
+	maker, err := NewPasetoPublicMaker(ring, Options{})
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.UserRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+
+	// A different key claiming the same key ID
+	impostor, err := NewPasetoPublicMaker(newTestKeyRing(t, "k1"), Options{})
+	require.NoError(t, err)
+	forged, _, err := impostor.CreateToken(util.RandomOwner(), util.AdminRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+
+	localMaker, err := NewPasetoMaker(util.RandomString(32), Options{})
+	require.NoError(t, err)
+	localToken, _, err := localMaker.CreateToken(util.RandomOwner(), util.UserRole, 0, nil, time.Minute)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{name: "Forged", token: forged},
+		{name: "Tampered", token: parts[0] + "." + parts[1] + "." + strings.ToUpper(parts[2]) + "." + parts[3]},
+		{name: "NoFooter", token: strings.Join(parts[:3], ".")},
+		{name: "LocalToken", token: localToken},
+		{name: "Garbage", token: util.RandomString(32)},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := maker.VerifyToken(tc.token)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestParseKeyRing(t *testing.T) {
+	seed1 := "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	seed2 := "HyAhIiMkJSYnKCkqKywtLi8wMTIzNDU2Nzg5Ojs8PT4="
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	public := base64.StdEncoding.EncodeToString(publicKey)
+
+	ring, err := ParseKeyRing("k2:"+seed2+", k1:"+seed1, "")
+	require.NoError(t, err)
+	require.Equal(t, "k2", ring.signingID)
+	require.Len(t, ring.PublicKeys(), 2)
+
+	testCases := []struct {
+		name        string
+		privateKeys string
+		publicKeys  string
+		errContains string
+	}{
+		{name: "NoPrivateKey", privateKeys: "", errContains: "at least one key"},
+		{name: "MissingID", privateKeys: seed1, errContains: "expected id:base64"},
+		{name: "BadBase64", privateKeys: "k1:not-base64!", errContains: "invalid key"},
+		{name: "ShortSeed", privateKeys: "k1:AAEC", errContains: "invalid key size"},
+		{name: "DuplicateID", privateKeys: "k1:" + seed1 + ",k1:" + seed2, errContains: "duplicate key ID"},
+		{name: "EmptyID", privateKeys: ":" + seed1, errContains: "invalid key ID"},
+		{name: "BadPublicKey", privateKeys: "k1:" + seed1, publicKeys: "k0:AAEC", errContains: "public keys"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseKeyRing(tc.privateKeys, tc.publicKeys)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errContains)
+		})
+	}
+
+	ring, err = ParseKeyRing("k1:"+seed1, "k0:"+public)
+	require.NoError(t, err)
+	key, ok := ring.PublicKey("k0")
+	require.True(t, ok)
+	require.Equal(t, publicKey, key)
+}
@@ -9,37 +9,97 @@ import (
 
 // Different types of error returned by the VerifyToken function
 var (
-	ErrInvalidToken = fmt.Errorf("token is invalid")
-	ErrExpiredToken = fmt.Errorf("token has expired")
+	ErrInvalidToken     = fmt.Errorf("token is invalid")
+	ErrExpiredToken     = fmt.Errorf("token has expired")
+	ErrTokenNotValidYet = fmt.Errorf("token is not valid yet")
+	ErrInvalidIssuer    = fmt.Errorf("token issuer is invalid")
+	ErrInvalidAudience  = fmt.Errorf("token audience is invalid")
 )
 
+// Options are the issuer and audience a maker puts in its tokens and expects back.
+// ClockSkew is how far the clocks of the services sharing the tokens may drift apart,
+// tokens are accepted that much before their not-before time and after their expiry.
+type Options struct {
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+}
+
+// Payload is the data carried by a token. Generation is the token generation of the user
+// when the token was issued, tokens of an older generation are no longer accepted.
+// Scopes restrict what the token may be used for.
 type Payload struct {
-	ID        uuid.UUID `json:"id"`
-	UserName  string    `json:"user_name"`
-	IssuedAt  time.Time `json:"issued_at"`
-	ExpiredAt time.Time `json:"expired_at"`
+	ID         uuid.UUID `json:"id"`
+	Issuer     string    `json:"issuer,omitempty"`
+	Audience   string    `json:"audience,omitempty"`
+	UserName   string    `json:"user_name"`
+	Role       string    `json:"role"`
+	Generation int32     `json:"generation"`
+	Scopes     []string  `json:"scopes,omitempty"`
+	IssuedAt   time.Time `json:"issued_at"`
+	NotBefore  time.Time `json:"not_before"`
+	ExpiredAt  time.Time `json:"expired_at"`
 }
 
-// NewPayload creates a new token payload with a specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload with a specific username, role, token generation, scopes and duration
+func NewPayload(username string, role string, generation int32, scopes []string, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	payload := &Payload{
-		ID:        tokenID,
-		UserName:  username,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		ID:         tokenID,
+		UserName:   username,
+		Role:       role,
+		Generation: generation,
+		Scopes:     scopes,
+		IssuedAt:   now,
+		NotBefore:  now,
+		ExpiredAt:  now.Add(duration),
+	}
+	return payload, nil
+}
+
+// newPayload creates a payload carrying the issuer and audience of the options
+func (options Options) newPayload(username string, role string, generation int32, scopes []string, duration time.Duration) (*Payload, error) {
+	payload, err := NewPayload(username, role, generation, scopes, duration)
+	if err != nil {
+		return nil, err
 	}
+
+	payload.Issuer = options.Issuer
+	payload.Audience = options.Audience
 	return payload, nil
 }
 
-// Valid checks if the token payload is valid or not
-func (payload *Payload) Valid() error {
-	if time.Now().After(payload.ExpiredAt) {
+// Valid checks if the token payload is valid or not.
+// The issuer and audience are only checked when the options set them.
+func (payload *Payload) Valid(options Options) error {
+	now := time.Now()
+
+	if now.After(payload.ExpiredAt.Add(options.ClockSkew)) {
 		return ErrExpiredToken
 	}
+	if now.Add(options.ClockSkew).Before(payload.NotBefore) {
+		return ErrTokenNotValidYet
+	}
+	if options.Issuer != "" && payload.Issuer != options.Issuer {
+		return ErrInvalidIssuer
+	}
+	if options.Audience != "" && payload.Audience != options.Audience {
+		return ErrInvalidAudience
+	}
 	return nil
 }
+
+// HasScope checks if the token was issued for the scope
+func (payload *Payload) HasScope(scope string) bool {
+	for _, s := range payload.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadValid(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name    string
+		payload Payload
+		options Options
+		err     error
+	}{
+		{
+			name:    "OK",
+			payload: Payload{NotBefore: now, ExpiredAt: now.Add(time.Minute)},
+		},
+		{
+			name:    "Expired",
+			payload: Payload{NotBefore: now.Add(-time.Hour), ExpiredAt: now.Add(-time.Second)},
+			err:     ErrExpiredToken,
+		},
+		{
+			name:    "ExpiredWithinSkew",
+			payload: Payload{NotBefore: now.Add(-time.Hour), ExpiredAt: now.Add(-time.Second)},
+			options: Options{ClockSkew: time.Minute},
+		},
+		{
+			name:    "ExpiredBeyondSkew",
+			payload: Payload{NotBefore: now.Add(-time.Hour), ExpiredAt: now.Add(-2 * time.Minute)},
+			options: Options{ClockSkew: time.Minute},
+			err:     ErrExpiredToken,
+		},
+		{
+			name:    "NotValidYet",
+			payload: Payload{NotBefore: now.Add(10 * time.Second), ExpiredAt: now.Add(time.Hour)},
+			err:     ErrTokenNotValidYet,
+		},
+		{
+			name:    "NotValidYetWithinSkew",
+			payload: Payload{NotBefore: now.Add(10 * time.Second), ExpiredAt: now.Add(time.Hour)},
+			options: Options{ClockSkew: time.Minute},
+		},
+		{
+			name:    "NoNotBefore",
+			payload: Payload{ExpiredAt: now.Add(time.Hour)},
+		},
+		{
+			name:    "Issuer",
+			payload: Payload{Issuer: "mef-api", ExpiredAt: now.Add(time.Hour)},
+			options: Options{Issuer: "mef-api"},
+		},
+		{
+			name:    "WrongIssuer",
+			payload: Payload{Issuer: "other", ExpiredAt: now.Add(time.Hour)},
+			options: Options{Issuer: "mef-api"},
+			err:     ErrInvalidIssuer,
+		},
+		{
+			name:    "Audience",
+			payload: Payload{Audience: "mef-api", ExpiredAt: now.Add(time.Hour)},
+			options: Options{Audience: "mef-api"},
+		},
+		{
+			name:    "WrongAudience",
+			payload: Payload{Audience: "other", ExpiredAt: now.Add(time.Hour)},
+			options: Options{Audience: "mef-api"},
+			err:     ErrInvalidAudience,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.payload.Valid(tc.options)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err.Error())
+		})
+	}
+}
@@ -8,11 +8,45 @@ import (
 
 //Holds all config vars
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver               string        `mapstructure:"DB_DRIVER"`
+	DBSource               string        `mapstructure:"DB_SOURCE"`
+	ServerAddress          string        `mapstructure:"SERVER_ADDRESS"`
+	TokenType              string        `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey      string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenPrivateKeys       string        `mapstructure:"TOKEN_PRIVATE_KEYS"`
+	TokenPublicKeys        string        `mapstructure:"TOKEN_PUBLIC_KEYS"`
+	TokenIssuer            string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience          string        `mapstructure:"TOKEN_AUDIENCE"`
+	TokenClockSkew         time.Duration `mapstructure:"TOKEN_CLOCK_SKEW"`
+	AccessTokenDuration    time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration   time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	DeletedPostRetention   time.Duration `mapstructure:"DELETED_POST_RETENTION"`
+	AppBaseURL             string        `mapstructure:"APP_BASE_URL"`
+	EmailSender            string        `mapstructure:"EMAIL_SENDER"`
+	EmailFromAddress       string        `mapstructure:"EMAIL_FROM_ADDRESS"`
+	EmailFilePath          string        `mapstructure:"EMAIL_FILE_PATH"`
+	SMTPHost               string        `mapstructure:"SMTP_HOST"`
+	SMTPPort               int           `mapstructure:"SMTP_PORT"`
+	SMTPUsername           string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword           string        `mapstructure:"SMTP_PASSWORD"`
+	VerifyEmailDuration    time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	PasswordResetDuration  time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	LoginMaxAttempts       int           `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginMaxAttemptsPerIP  int           `mapstructure:"LOGIN_MAX_ATTEMPTS_PER_IP"`
+	LoginBackoffBase       time.Duration `mapstructure:"LOGIN_BACKOFF_BASE"`
+	LoginBackoffMax        time.Duration `mapstructure:"LOGIN_BACKOFF_MAX"`
+	LoginLockoutDuration   time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	RateLimitBackend       string        `mapstructure:"RATE_LIMIT_BACKEND"`
+	RateLimitPublic        string        `mapstructure:"RATE_LIMIT_PUBLIC"`
+	RateLimitAccount       string        `mapstructure:"RATE_LIMIT_ACCOUNT"`
+	RateLimitUser          string        `mapstructure:"RATE_LIMIT_USER"`
+	RateLimitPosting       string        `mapstructure:"RATE_LIMIT_POSTING"`
+	TOTPIssuer             string        `mapstructure:"TOTP_ISSUER"`
+	TwoFactorTokenDuration time.Duration `mapstructure:"TWO_FACTOR_TOKEN_DURATION"`
+	TwoFactorSymmetricKey  string        `mapstructure:"TWO_FACTOR_SYMMETRIC_KEY"`
+	OIDCProviders          string        `mapstructure:"OIDC_PROVIDERS"`
+	OIDCStateDuration      time.Duration `mapstructure:"OIDC_STATE_DURATION"`
+	OIDCLinkByEmail        string        `mapstructure:"OIDC_LINK_BY_EMAIL"`
 }
 
 //Read configuration values from a config file or env vars
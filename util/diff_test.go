@@ -0,0 +1,123 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	testCases := []struct {
+		name string
+		from string
+		to   string
+		diff string
+	}{
+		{
+			name: "Equal",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			diff: "",
+		},
+		{
+			name: "ChangedLine",
+			from: "a\nb\nc",
+			to:   "a\nx\nc",
+			diff: "--- from\n+++ to\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name: "FromEmpty",
+			from: "",
+			to:   "a\nb",
+			diff: "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "ToEmpty",
+			from: "a",
+			to:   "",
+			diff: "--- from\n+++ to\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "SeparateHunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			to:   "0\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n13",
+			diff: "--- from\n+++ to\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+13\n",
+		},
+		{
+			name: "MergedHunk",
+			from: "1\n2\n3\n4\n5\n6\n7\n8",
+			to:   "0\n2\n3\n4\n5\n6\n7\n9",
+			diff: "--- from\n+++ to\n" +
+				"@@ -1,8 +1,8 @@\n-1\n+0\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+9\n",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.diff, UnifiedDiff("from", "to", tc.from, tc.to))
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	//Lines from a small alphabet so that both texts share many of them
+	randomLines := func(n int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = string(rune('a' + RandomInt(0, 3)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 50; i++ {
+		a, b := randomLines(int(RandomInt(0, 40))), randomLines(int(RandomInt(0, 40)))
+
+		var gotA, gotB []string
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.line)
+			}
+		}
+
+		//Dropping the added lines gives back a, dropping the removed ones gives b
+		require.Equal(t, len(a), len(gotA))
+		require.Equal(t, len(b), len(gotB))
+		for j := range a {
+			require.Equal(t, a[j], gotA[j])
+		}
+		for j := range b {
+			require.Equal(t, b[j], gotB[j])
+		}
+	}
+}
+
+func TestDiffLinesTooManyEdits(t *testing.T) {
+	var from, to strings.Builder
+	from.WriteString("first\n")
+	to.WriteString("first\n")
+	for i := 0; i < maxDiffEdits; i++ {
+		fmt.Fprintf(&from, "from %d\n", i)
+		fmt.Fprintf(&to, "to %d\n", i)
+	}
+	from.WriteString("last\n")
+	to.WriteString("last\n")
+
+	ops := diffLines(splitLines(from.String()), splitLines(to.String()))
+	require.Len(t, ops, 2*maxDiffEdits+2)
+
+	//The changed lines are replaced as one block between the common lines
+	require.Equal(t, diffOp{' ', "first"}, ops[0])
+	require.Equal(t, diffOp{'-', "from 0"}, ops[1])
+	require.Equal(t, diffOp{'-', fmt.Sprintf("from %d", maxDiffEdits-1)}, ops[maxDiffEdits])
+	require.Equal(t, diffOp{'+', "to 0"}, ops[maxDiffEdits+1])
+	require.Equal(t, diffOp{' ', "last"}, ops[len(ops)-1])
+}
@@ -0,0 +1,8 @@
+package util
+
+// Roles a user of the forum can have
+const (
+	UserRole      = "user"
+	ModeratorRole = "moderator"
+	AdminRole     = "admin"
+)
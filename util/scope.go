@@ -0,0 +1,32 @@
+package util
+
+// Scopes a token can be issued for, a token is only accepted on the routes of its scopes
+const (
+	PostsWriteScope      = "posts:write"
+	CommentsWriteScope   = "comments:write"
+	AccountScope         = "account"
+	UsersAdminScope      = "users:admin"
+	CategoriesAdminScope = "categories:admin"
+)
+
+// RefreshScope is the only scope of a refresh token, it renews access tokens and is not accepted on any route
+const RefreshScope = "refresh"
+
+// RoleScopes returns every scope a user with the role may use, the scopes of a login
+func RoleScopes(role string) []string {
+	scopes := []string{PostsWriteScope, CommentsWriteScope, AccountScope}
+	if role == AdminRole {
+		scopes = append(scopes, UsersAdminScope, CategoriesAdminScope)
+	}
+	return scopes
+}
+
+// IsScopeAllowed checks if a user with the role may use the scope
+func IsScopeAllowed(role string, scope string) bool {
+	for _, s := range RoleScopes(role) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}